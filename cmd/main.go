@@ -2,44 +2,125 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cache"
+	"github.com/rcbadiale/go-cloud-run/internals/config"
 	"github.com/rcbadiale/go-cloud-run/internals/handlers"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load()
 	if err != nil {
-		log.Println("error loading .env file, will use environment variables")
+		log.Fatalln(err)
 	}
+	log.Printf("effective configuration:\n%s", cfg.Summary())
 
-	log.Println("starting server on port 8080")
-	runServer()
+	runServer(cfg)
 }
 
-func runServer() {
+func runServer(cfg *config.Config) {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
-	weatherApiKey := os.Getenv("WEATHER_API_KEY")
-	weatherHandler := handlers.NewWeatherHandler(weatherApiKey)
+	newClient := newUpstreamClientFactory(cfg)
+	cepProviders, err := services.NewCEPProviders(cfg.CEPProviders, newClient)
+	if err != nil {
+		log.Fatalln("error configuring CEP providers: ", err)
+	}
+	cepService, err := services.NewCEPServiceWithStrategy(cfg.CEPStrategy, cepProviders)
+	if err != nil {
+		log.Fatalln("error configuring CEP strategy: ", err)
+	}
+	cepSearchService := services.NewCEPSearchService(cepProviders)
+	weatherService, err := services.NewWeatherService(cfg.WeatherProvider, cfg.WeatherAPIKey, newClient)
+	if err != nil {
+		log.Fatalln("error configuring weather provider: ", err)
+	}
+	forecastService, _ := weatherService.(services.ForecastService)
+	historyService, _ := weatherService.(services.HistoryService)
+	cacheStore := newCacheStore(cfg.Cache)
+	cepService = services.NewCachedCEPService(
+		services.NewCoalescingCEPService(cepService),
+		cacheStore,
+		cfg.Cache.CEPTTL,
+		cfg.Cache.CEPNotFoundTTL,
+	)
+	weatherService = services.NewCachedWeatherService(
+		services.NewCoalescingWeatherService(weatherService),
+		cacheStore,
+		cfg.Cache.WeatherTTL,
+	)
+	geocoder := services.FallbackGeocoder{services.NewIBGEGeocoder(), services.NewOpenMeteoGeocoder(newClient("openmeteo-geocoding"))}
+	weatherHandler := handlers.NewWeatherHandler(cepService, weatherService, geocoder)
+	weatherHandler.ForecastService = forecastService
+	weatherHandler.HistoryService = historyService
+	weatherHandler.MunicipalityService = services.NewIBGEService(newClient(services.ProviderIBGE))
+	weatherHandler.BatchWorkers = cfg.BatchWorkers
+	weatherHandler.BatchMaxItems = cfg.BatchMaxItems
+	weatherHandler.StreamWorkers = cfg.StreamWorkers
+	addressHandler := handlers.NewAddressHandler(cepService, cepSearchService, services.NewIBGEGeocoder())
 	r.With(addContext).Get("/weather/{zipCode}", weatherHandler.GetWeather)
-	err := http.ListenAndServe(":8080", r)
+	r.With(addContext).Get("/weather/{zipCode}/forecast", weatherHandler.GetForecast)
+	r.With(addContext).Get("/weather/{zipCode}/history", weatherHandler.GetHistory)
+	r.With(addContext).Get("/weather/city/{uf}/{name}", weatherHandler.GetWeatherByCity)
+	r.With(addContext).Get("/weather/coords", weatherHandler.GetWeatherByCoordinates)
+	r.With(addContext).Get("/weather/ibge/{code}", weatherHandler.GetWeatherByIBGE)
+	r.With(addContext).Post("/weather/batch", weatherHandler.GetWeatherBatch)
+	r.With(addContext).Post("/weather/stream", weatherHandler.StreamWeather)
+	r.With(addContext).Get("/address/{zipCode}", addressHandler.GetAddress)
+	r.With(addContext).Get("/cep/search", addressHandler.SearchCEP)
+	r.Handle("/debug/vars", expvar.Handler())
+
+	// Cloud Run sends SIGTERM before stopping the instance
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	server := internals.NewServer(r, cfg.Server)
+	log.Printf("starting server on port %d\n", server.Settings.Port)
+	err = server.Run(ctx)
 	if err != nil {
-		log.Println("error starting server: ", err)
+		log.Println("error running server: ", err)
 	}
 }
 
-type contextKey string
+// newUpstreamClientFactory creates a circuit breaker for each upstream,
+// retrying failed requests before they count against the breaker
+func newUpstreamClientFactory(cfg *config.Config) internals.ClientFactory {
+	return func(name string) internals.HTTPClient {
+		retry := internals.NewRetryClient(&http.Client{}, cfg.Retry)
+		return internals.NewCircuitBreakerClient(name, retry, cfg.Breaker)
+	}
+}
+
+// newCacheStore creates the cache shared by the services, backed by Redis
+// when REDIS_ADDR is set and by memory otherwise or while Redis is down
+func newCacheStore(settings config.CacheSettings) cache.Store {
+	memory := cache.NewMemory(settings.Size)
+	if settings.RedisAddr == "" {
+		return memory
+	}
+	redis := cache.NewRedis(settings.RedisAddr, settings.RedisPassword, settings.RedisDB, settings.RedisPrefix)
+	err := redis.Ping(context.Background())
+	if err != nil {
+		log.Println("error connecting to redis, will use in-memory cache until it is reachable: ", err)
+	}
+	return cache.NewFallback(redis, memory)
+}
 
 func addContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.WithValue(r.Context(), contextKey("request_id"), uuid.New().String())
+		requestID := uuid.New().String()
+		ctx := context.WithValue(r.Context(), handlers.RequestIDKey, requestID)
+		w.Header().Set("X-Request-Id", requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const Fallback_Cooldown = 30 * time.Second
+
+// Fallback is a Store using Primary while it is reachable. When Primary
+// fails, Secondary is used for Cooldown before Primary is tried again.
+type Fallback struct {
+	Primary   Store
+	Secondary Store
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// NewFallback creates a new Fallback store
+func NewFallback(primary, secondary Store) *Fallback {
+	return &Fallback{Primary: primary, Secondary: secondary, Cooldown: Fallback_Cooldown}
+}
+
+// Get returns the value stored for key
+func (f *Fallback) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if f.primaryUp() {
+		value, ok, err := f.Primary.Get(ctx, key)
+		if err == nil {
+			return value, ok, nil
+		}
+		f.markDown(err)
+	}
+	return f.Secondary.Get(ctx, key)
+}
+
+// Set stores value for key during ttl
+func (f *Fallback) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if f.primaryUp() {
+		err := f.Primary.Set(ctx, key, value, ttl)
+		if err == nil {
+			return nil
+		}
+		f.markDown(err)
+	}
+	return f.Secondary.Set(ctx, key, value, ttl)
+}
+
+func (f *Fallback) primaryUp() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !time.Now().Before(f.downUntil)
+}
+
+func (f *Fallback) markDown(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	log.Printf("cache store unavailable, using fallback for %s: %v\n", f.Cooldown, err)
+	f.downUntil = time.Now().Add(f.Cooldown)
+}
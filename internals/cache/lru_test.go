@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func newTestLRU(capacity int) (*LRU[string, int], *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)}
+	lru := NewLRU[string, int](capacity)
+	lru.now = clock.Now
+	return lru, clock
+}
+
+func TestLRUGetSet(t *testing.T) {
+	lru, _ := newTestLRU(2)
+
+	lru.Set("a", 1, time.Minute)
+	value, ok := lru.Get("a")
+
+	assert := assert.New(t)
+	assert.True(ok)
+	assert.Equal(1, value)
+
+	lru.Set("a", 2, time.Minute)
+	value, _ = lru.Get("a")
+	assert.Equal(2, value)
+	assert.Equal(1, lru.Len())
+
+	_, ok = lru.Get("b")
+	assert.False(ok)
+}
+
+func TestLRUExpiration(t *testing.T) {
+	lru, clock := newTestLRU(2)
+
+	lru.Set("a", 1, time.Minute)
+	clock.now = clock.now.Add(59 * time.Second)
+	_, ok := lru.Get("a")
+	assert.True(t, ok)
+
+	clock.now = clock.now.Add(time.Second)
+	_, ok = lru.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, lru.Len())
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	lru, _ := newTestLRU(2)
+
+	lru.Set("a", 1, time.Minute)
+	lru.Set("b", 2, time.Minute)
+	lru.Get("a")
+	lru.Set("c", 3, time.Minute)
+
+	assert := assert.New(t)
+	assert.Equal(2, lru.Len())
+	_, ok := lru.Get("b")
+	assert.False(ok)
+	_, ok = lru.Get("a")
+	assert.True(ok)
+	_, ok = lru.Get("c")
+	assert.True(ok)
+}
+
+func TestLRUDelete(t *testing.T) {
+	lru, _ := newTestLRU(2)
+
+	lru.Set("a", 1, time.Minute)
+	lru.Delete("a")
+	lru.Delete("missing")
+
+	_, ok := lru.Get("a")
+	assert.False(t, ok)
+}
+
+func TestLRUConcurrentAccess(t *testing.T) {
+	lru := NewLRU[string, int](10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprint(i % 20)
+			lru.Set(key, i, time.Minute)
+			lru.Get(key)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, lru.Len(), 10)
+}
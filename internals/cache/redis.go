@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Redis_Timeout  = 500 * time.Millisecond
+	Redis_PoolSize = 10
+)
+
+// Redis is a Store speaking the Redis protocol (RESP), compatible with
+// Redis, Memorystore and other servers implementing GET and SET
+type Redis struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix is prepended to every key, so the instance can be shared
+	Prefix  string
+	Timeout time.Duration
+	idle    chan *redisConn
+}
+
+// RedisError is an error reply sent by the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis creates a new Redis store for the server at addr
+func NewRedis(addr, password string, db int, prefix string) *Redis {
+	return &Redis{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+		Prefix:   prefix,
+		Timeout:  Redis_Timeout,
+		idle:     make(chan *redisConn, Redis_PoolSize),
+	}
+}
+
+// Ping checks that the server is reachable
+func (r *Redis) Ping(ctx context.Context) error {
+	reply, err := r.do(ctx, "PING")
+	if err != nil {
+		return err
+	} else if reply != "PONG" {
+		return fmt.Errorf("redis: unexpected PING reply %v", reply)
+	}
+	return nil
+}
+
+// Get returns the value stored for key
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", r.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	switch value := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		return value, true, nil
+	default:
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+}
+
+// Set stores value for key during ttl
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", r.Prefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case c := <-r.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a command and reads its reply, discarding the connection on
+// anything but a server error reply. An idle connection closed by the
+// server is retried once on a new connection.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	select {
+	case c := <-r.idle:
+		reply, err := r.send(ctx, c, args...)
+		if err == nil || isRedisError(err) || ctx.Err() != nil {
+			return reply, err
+		}
+	default:
+	}
+
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.send(ctx, c, args...)
+}
+
+func (r *Redis) send(ctx context.Context, c *redisConn, args ...string) (any, error) {
+	reply, err := c.command(ctx, r.Timeout, args...)
+	if err != nil && !isRedisError(err) {
+		c.conn.Close()
+		return nil, err
+	}
+	r.release(c)
+	return reply, err
+}
+
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: r.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if r.Password != "" {
+		if _, err := c.command(ctx, r.Timeout, "AUTH", r.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.DB != 0 {
+		if _, err := c.command(ctx, r.Timeout, "SELECT", strconv.Itoa(r.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (r *Redis) release(c *redisConn) {
+	select {
+	case r.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+func isRedisError(err error) bool {
+	var redisErr RedisError
+	return errors.As(err, &redisErr)
+}
+
+func (c *redisConn) command(ctx context.Context, timeout time.Duration, args ...string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.conn.SetDeadline(deadline)
+
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, command.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// readReply reads a RESP reply: simple strings are returned as string,
+// bulk strings as []byte, integers as int64 and arrays as []any
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	} else if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		} else if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		} else if size < 0 {
+			return nil, nil
+		}
+		values := make([]any, size)
+		for i := range values {
+			if values[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is an in-process server implementing the RESP commands used by
+// the Redis store
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		args := reply.([]any)
+		command := strings.ToUpper(string(args[0].([]byte)))
+		f.mu.Lock()
+		f.commands = append(f.commands, command)
+		f.mu.Unlock()
+
+		if !authenticated && command != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch command {
+		case "AUTH":
+			if string(args[1].([]byte)) != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authenticated = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "GET":
+			value, ok := f.get(string(args[1].([]byte)))
+			if !ok {
+				fmt.Fprint(conn, "$-1\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+		case "SET":
+			key, value := string(args[1].([]byte)), string(args[2].([]byte))
+			var expiresAt time.Time
+			if len(args) == 5 && strings.ToUpper(string(args[3].([]byte))) == "PX" {
+				ms, _ := strconv.Atoi(string(args[4].([]byte)))
+				expiresAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			f.mu.Lock()
+			f.values[key] = value
+			f.expires[key] = expiresAt
+			f.mu.Unlock()
+			fmt.Fprint(conn, "+OK\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", command)
+		}
+	}
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.values[key]
+	if expiresAt := f.expires[key]; ok && !expiresAt.IsZero() && !time.Now().Before(expiresAt) {
+		delete(f.values, key)
+		return "", false
+	}
+	return value, ok
+}
+
+func (f *fakeRedis) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestRedisGetSet(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := NewRedis(server.Addr(), "", 0, "test:")
+	defer store.Close()
+	ctx := context.Background()
+
+	assert := assert.New(t)
+	assert.Nil(store.Ping(ctx))
+
+	_, ok, err := store.Get(ctx, "missing")
+	assert.Nil(err)
+	assert.False(ok)
+
+	assert.Nil(store.Set(ctx, "key", []byte("value\r\nwith newline"), time.Minute))
+	value, ok, err := store.Get(ctx, "key")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal("value\r\nwith newline", string(value))
+
+	stored, ok := server.get("test:key")
+	assert.True(ok)
+	assert.Equal("value\r\nwith newline", stored)
+}
+
+func TestRedisExpiration(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := NewRedis(server.Addr(), "", 0, "")
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Set(ctx, "key", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	_, ok, err := store.Get(ctx, "key")
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedisAuthAndSelect(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	store := NewRedis(server.Addr(), "secret", 2, "")
+	defer store.Close()
+
+	assert := assert.New(t)
+	assert.Nil(store.Ping(context.Background()))
+	assert.Nil(store.Ping(context.Background()))
+	assert.Equal([]string{"AUTH", "SELECT", "PING", "PING"}, server.Commands())
+
+	wrong := NewRedis(server.Addr(), "wrong", 0, "")
+	assert.Equal(RedisError("WRONGPASS invalid password"), wrong.Ping(context.Background()))
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	store := NewRedis(addr, "", 0, "")
+	_, _, err := store.Get(context.Background(), "key")
+
+	assert.Error(t, err)
+}
+
+func TestRedisReconnectsAfterServerClose(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := NewRedis(server.Addr(), "", 0, "")
+	defer store.Close()
+	ctx := context.Background()
+
+	assert.Nil(t, store.Set(ctx, "key", []byte("value"), time.Minute))
+	// Drop the pooled connection from the server side
+	c := <-store.idle
+	c.conn.Close()
+	store.idle <- c
+
+	value, ok, err := store.Get(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+}
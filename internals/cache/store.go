@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the byte oriented cache used by the caching services, so the
+// same values can be kept in memory or in a shared backend such as Redis
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Memory is a Store backed by an in-process LRU
+type Memory struct {
+	lru *LRU[string, []byte]
+}
+
+// NewMemory creates a new Memory store holding at most size entries
+func NewMemory(size int) *Memory {
+	return &Memory{lru: NewLRU[string, []byte](size)}
+}
+
+// Get returns the value stored for key, if present and not expired
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := m.lru.Get(key)
+	return value, ok, nil
+}
+
+// Set stores value for key during ttl
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lru.Set(key, value, ttl)
+	return nil
+}
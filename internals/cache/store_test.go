@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a Store whose every call fails
+type failingStore struct {
+	calls int
+}
+
+func (f *failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	f.calls++
+	return nil, false, errors.New("connection refused")
+}
+
+func (f *failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.calls++
+	return errors.New("connection refused")
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemory(10)
+	ctx := context.Background()
+
+	assert := assert.New(t)
+	assert.Nil(store.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := store.Get(ctx, "key")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal([]byte("value"), value)
+
+	_, ok, _ = store.Get(ctx, "missing")
+	assert.False(ok)
+}
+
+func TestFallbackUsesPrimary(t *testing.T) {
+	server := newFakeRedis(t, "")
+	primary := NewRedis(server.Addr(), "", 0, "")
+	secondary := NewMemory(10)
+	store := NewFallback(primary, secondary)
+	ctx := context.Background()
+
+	store.Set(ctx, "key", []byte("value"), time.Minute)
+
+	assert := assert.New(t)
+	_, ok := server.get("key")
+	assert.True(ok)
+	_, ok, _ = secondary.Get(ctx, "key")
+	assert.False(ok)
+}
+
+func TestFallbackWhenPrimaryIsUnreachable(t *testing.T) {
+	primary := &failingStore{}
+	store := NewFallback(primary, NewMemory(10))
+	ctx := context.Background()
+
+	assert := assert.New(t)
+	assert.Nil(store.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := store.Get(ctx, "key")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal([]byte("value"), value)
+	// The primary is skipped during the cooldown
+	assert.Equal(1, primary.calls)
+
+	store.Cooldown = 0
+	store.markDown(errors.New("connection refused"))
+	store.Get(ctx, "key")
+	assert.Equal(2, primary.calls)
+}
@@ -0,0 +1,121 @@
+// Package cep parses and validates Brazilian postal codes (CEP).
+//
+// A CEP has no check digit, so validation is limited to its shape and to the
+// ranges the Correios assign to each state (UF).
+package cep
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Error is the reason a value is not a valid CEP
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrNonDigit   Error = "the CEP must only have digits, optionally separated by '-', '.' or spaces"
+	ErrLength     Error = "the CEP must have 8 digits"
+	ErrOutOfRange Error = "the CEP is not in the range of any state"
+)
+
+// CEP is a validated CEP, holding its 8 digits
+type CEP string
+
+// ufRange is a range of CEP prefixes, the first 5 digits, assigned to a UF
+type ufRange struct {
+	uf       string
+	from, to int
+}
+
+// ufRanges are the CEP prefixes of each UF, as assigned by the Correios.
+// Prefixes below 01000 are not assigned
+var ufRanges = []ufRange{
+	{"SP", 1000, 19999},
+	{"RJ", 20000, 28999},
+	{"ES", 29000, 29999},
+	{"MG", 30000, 39999},
+	{"BA", 40000, 48999},
+	{"SE", 49000, 49999},
+	{"PE", 50000, 56999},
+	{"AL", 57000, 57999},
+	{"PB", 58000, 58999},
+	{"RN", 59000, 59999},
+	{"CE", 60000, 63999},
+	{"PI", 64000, 64999},
+	{"MA", 65000, 65999},
+	{"PA", 66000, 68899},
+	{"AP", 68900, 68999},
+	{"AM", 69000, 69299},
+	{"RR", 69300, 69399},
+	{"AM", 69400, 69899},
+	{"AC", 69900, 69999},
+	{"DF", 70000, 72799},
+	{"GO", 72800, 72999},
+	{"DF", 73000, 73699},
+	{"GO", 73700, 76799},
+	{"RO", 76800, 76999},
+	{"TO", 77000, 77999},
+	{"MT", 78000, 78899},
+	{"MS", 79000, 79999},
+	{"PR", 80000, 87999},
+	{"SC", 88000, 89999},
+	{"RS", 90000, 99999},
+}
+
+// Parse validates value as a CEP, ignoring '-', '.' and spaces between its
+// digits, so "01310-100", "01.310-100" and "01310 100" are the same CEP
+func Parse(value string) (CEP, error) {
+	var digits strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == '.' || r == ' ':
+		default:
+			return "", ErrNonDigit
+		}
+	}
+	if digits.Len() != 8 {
+		return "", ErrLength
+	}
+	cep := CEP(digits.String())
+	if cep.UF() == "" {
+		return "", ErrOutOfRange
+	}
+	return cep, nil
+}
+
+// Digits returns the 8 digits of the CEP, as expected by the address providers
+func (c CEP) Digits() string {
+	return string(c)
+}
+
+// String returns the CEP in its canonical 00000-000 format
+func (c CEP) String() string {
+	if len(c) != 8 {
+		return string(c)
+	}
+	return string(c[:5]) + "-" + string(c[5:])
+}
+
+// UF returns the state the CEP is assigned to, or "" when it is not in the
+// range of any state
+func (c CEP) UF() string {
+	if len(c) != 8 {
+		return ""
+	}
+	prefix, err := strconv.Atoi(string(c[:5]))
+	if err != nil {
+		return ""
+	}
+	for _, r := range ufRanges {
+		if prefix >= r.from && prefix <= r.to {
+			return r.uf
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package cep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	for _, value := range []string{"01310100", "01310-100", "01.310-100", "01310 100", " 01310-100 "} {
+		cep, err := Parse(value)
+		assert.Nil(err, value)
+		assert.Equal(CEP("01310100"), cep, value)
+		assert.Equal("01310-100", cep.String(), value)
+		assert.Equal("01310100", cep.Digits(), value)
+		assert.Equal("SP", cep.UF(), value)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	assert := assert.New(t)
+	for value, expected := range map[string]error{
+		"abcdefgh":  ErrNonDigit,
+		"01310_100": ErrNonDigit,
+		"0131010a":  ErrNonDigit,
+		"":          ErrLength,
+		"0131010":   ErrLength,
+		"013101000": ErrLength,
+		"00000000":  ErrOutOfRange,
+		"00999-999": ErrOutOfRange,
+		"０１３１０１００":  ErrNonDigit,
+	} {
+		_, err := Parse(value)
+		assert.ErrorIs(err, expected, value)
+	}
+}
+
+func TestUF(t *testing.T) {
+	assert := assert.New(t)
+	for value, uf := range map[string]string{
+		"01000000": "SP",
+		"19999999": "SP",
+		"20040020": "RJ",
+		"68900000": "AP",
+		"69300000": "RR",
+		"69400000": "AM",
+		"70040010": "DF",
+		"72800000": "GO",
+		"73000000": "DF",
+		"76800000": "RO",
+		"88010000": "SC",
+		"99999999": "RS",
+	} {
+		cep, err := Parse(value)
+		assert.Nil(err, value)
+		assert.Equal(uf, cep.UF(), value)
+	}
+	assert.Equal("", CEP("invalid").UF())
+}
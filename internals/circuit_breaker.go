@@ -0,0 +1,207 @@
+package internals
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	CircuitBreaker_FailureThreshold = 5
+	CircuitBreaker_OpenTimeout      = 30 * time.Second
+	CircuitBreaker_HalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned, wrapped in a CircuitOpenError, for requests
+// rejected while a circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breakerMetrics exposes the breakers state on /debug/vars
+var breakerMetrics = expvar.NewMap("circuit_breakers")
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitOpenError is returned for requests rejected by an open breaker
+type CircuitOpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s: %v, retry after %s", e.Name, ErrCircuitOpen, e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerSettings configures a CircuitBreakerClient
+type BreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures opening the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing again
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probe requests allowed when half-open
+	HalfOpenRequests int
+}
+
+// DefaultBreakerSettings returns the settings used when none are configured
+func DefaultBreakerSettings() BreakerSettings {
+	return BreakerSettings{
+		FailureThreshold: CircuitBreaker_FailureThreshold,
+		OpenTimeout:      CircuitBreaker_OpenTimeout,
+		HalfOpenRequests: CircuitBreaker_HalfOpenRequests,
+	}
+}
+
+// CircuitBreakerClient wraps an HTTPClient with a circuit breaker. Transport
+// errors and 5xx responses count as failures; after FailureThreshold
+// consecutive failures requests fail fast until OpenTimeout has passed,
+// then HalfOpenRequests probes decide whether the breaker closes again.
+type CircuitBreakerClient struct {
+	Name     string
+	Client   HTTPClient
+	Settings BreakerSettings
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+	now      func() time.Time
+}
+
+// NewCircuitBreakerClient creates a new CircuitBreakerClient named after the
+// upstream it protects
+func NewCircuitBreakerClient(name string, client HTTPClient, settings BreakerSettings) *CircuitBreakerClient {
+	breaker := &CircuitBreakerClient{
+		Name:     name,
+		Client:   client,
+		Settings: settings,
+		now:      time.Now,
+	}
+	breaker.publish()
+	return breaker
+}
+
+// Do sends the request unless the breaker is open
+func (c *CircuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.allow(); err != nil {
+		breakerMetrics.Add(c.Name+".rejected", 1)
+		return nil, err
+	}
+
+	resp, err := c.Client.Do(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// The caller gave up, which says nothing about the upstream health
+		c.release()
+	case err != nil || resp.StatusCode >= 500:
+		c.onFailure()
+	default:
+		c.onSuccess()
+	}
+	return resp, err
+}
+
+// State returns the current breaker state
+func (c *CircuitBreakerClient) State() BreakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh()
+	return c.state
+}
+
+func (c *CircuitBreakerClient) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh()
+
+	switch c.state {
+	case StateOpen:
+		return &CircuitOpenError{Name: c.Name, RetryAfter: c.openedAt.Add(c.Settings.OpenTimeout).Sub(c.now())}
+	case StateHalfOpen:
+		if c.probes >= max(c.Settings.HalfOpenRequests, 1) {
+			return &CircuitOpenError{Name: c.Name, RetryAfter: time.Second}
+		}
+		c.probes++
+	}
+	return nil
+}
+
+func (c *CircuitBreakerClient) onSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	if c.state == StateHalfOpen {
+		c.transition(StateClosed)
+	}
+}
+
+func (c *CircuitBreakerClient) onFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.state == StateHalfOpen || c.failures >= c.Settings.FailureThreshold {
+		c.openedAt = c.now()
+		c.transition(StateOpen)
+	}
+}
+
+func (c *CircuitBreakerClient) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == StateHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// refresh moves an open breaker to half-open once OpenTimeout has passed
+func (c *CircuitBreakerClient) refresh() {
+	if c.state == StateOpen && !c.now().Before(c.openedAt.Add(c.Settings.OpenTimeout)) {
+		c.transition(StateHalfOpen)
+	}
+}
+
+func (c *CircuitBreakerClient) transition(state BreakerState) {
+	if c.state == state {
+		return
+	}
+	log.Printf("circuit breaker %s: %s -> %s\n", c.Name, c.state, state)
+	c.state = state
+	c.probes = 0
+	if state == StateClosed {
+		c.failures = 0
+	}
+	breakerMetrics.Add(c.Name+".transitions", 1)
+	c.publish()
+}
+
+func (c *CircuitBreakerClient) publish() {
+	state := new(expvar.String)
+	state.Set(c.state.String())
+	breakerMetrics.Set(c.Name+".state", state)
+}
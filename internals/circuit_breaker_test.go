@@ -0,0 +1,192 @@
+package internals
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubClient answers every request with status, or err when set
+type stubClient struct {
+	status int
+	err    error
+	calls  int
+}
+
+func (s *stubClient) Do(req *http.Request) (*http.Response, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{StatusCode: s.status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func newTestBreaker(name string, client HTTPClient) (*CircuitBreakerClient, *time.Time) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreakerClient(name, client, BreakerSettings{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 1,
+	})
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func doRequest(client HTTPClient) (*http.Response, error) {
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream/", nil)
+	return client.Do(req)
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	upstream := &stubClient{status: http.StatusServiceUnavailable}
+	breaker, now := newTestBreaker("test-open", upstream)
+
+	for i := 0; i < 3; i++ {
+		doRequest(breaker)
+	}
+	assert := assert.New(t)
+	assert.Equal(StateOpen, breaker.State())
+
+	*now = now.Add(4 * time.Second)
+	_, err := doRequest(breaker)
+	assert.ErrorIs(err, ErrCircuitOpen)
+	var circuitErr *CircuitOpenError
+	assert.ErrorAs(err, &circuitErr)
+	assert.Equal("test-open", circuitErr.Name)
+	assert.Equal(6*time.Second, circuitErr.RetryAfter)
+	assert.Equal(3, upstream.calls)
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	upstream := &stubClient{err: errors.New("connection reset")}
+	breaker, _ := newTestBreaker("test-reset", upstream)
+
+	doRequest(breaker)
+	doRequest(breaker)
+	upstream.err = nil
+	upstream.status = http.StatusOK
+	doRequest(breaker)
+	upstream.err = errors.New("connection reset")
+	doRequest(breaker)
+	doRequest(breaker)
+
+	assert.Equal(t, StateClosed, breaker.State())
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	upstream := &stubClient{status: http.StatusNotFound}
+	breaker, _ := newTestBreaker("test-4xx", upstream)
+
+	for i := 0; i < 5; i++ {
+		doRequest(breaker)
+	}
+
+	assert.Equal(t, StateClosed, breaker.State())
+}
+
+func TestCircuitBreakerIgnoresCallerCancellation(t *testing.T) {
+	upstream := &stubClient{err: context.Canceled}
+	breaker, _ := newTestBreaker("test-cancel", upstream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream/", nil)
+	for i := 0; i < 5; i++ {
+		breaker.Do(req)
+	}
+
+	assert.Equal(t, StateClosed, breaker.State())
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	upstream := &stubClient{status: http.StatusBadGateway}
+	breaker, now := newTestBreaker("test-half-open", upstream)
+	for i := 0; i < 3; i++ {
+		doRequest(breaker)
+	}
+
+	assert := assert.New(t)
+	*now = now.Add(10 * time.Second)
+	assert.Equal(StateHalfOpen, breaker.State())
+
+	// A failed probe opens the breaker again
+	doRequest(breaker)
+	assert.Equal(StateOpen, breaker.State())
+	assert.Equal(4, upstream.calls)
+
+	// A successful probe closes it
+	*now = now.Add(10 * time.Second)
+	upstream.status = http.StatusOK
+	_, err := doRequest(breaker)
+	assert.Nil(err)
+	assert.Equal(StateClosed, breaker.State())
+}
+
+func TestCircuitBreakerHalfOpenLimitsProbes(t *testing.T) {
+	release := make(chan struct{})
+	upstream := &blockingClient{release: release, started: make(chan struct{}, 1)}
+	breaker, now := newTestBreaker("test-probes", upstream)
+	breaker.state = StateOpen
+	breaker.openedAt = *now
+	*now = now.Add(10 * time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		doRequest(breaker)
+		close(done)
+	}()
+	<-upstream.started
+
+	_, err := doRequest(breaker)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	close(release)
+	<-done
+	assert.Equal(t, StateClosed, breaker.State())
+}
+
+// breakerCount returns a counter of breakerMetrics, which is shared by every
+// run of the tests
+func breakerCount(name string) int64 {
+	if count, ok := breakerMetrics.Get(name).(*expvar.Int); ok {
+		return count.Value()
+	}
+	return 0
+}
+
+func TestCircuitBreakerMetrics(t *testing.T) {
+	upstream := &stubClient{status: http.StatusInternalServerError}
+	breaker, _ := newTestBreaker("test-metrics", upstream)
+	transitions := breakerCount("test-metrics.transitions")
+	rejected := breakerCount("test-metrics.rejected")
+
+	assert := assert.New(t)
+	assert.Equal(`"closed"`, breakerMetrics.Get("test-metrics.state").String())
+
+	for i := 0; i < 4; i++ {
+		doRequest(breaker)
+	}
+
+	assert.Equal(`"open"`, breakerMetrics.Get("test-metrics.state").String())
+	assert.Equal(transitions+1, breakerCount("test-metrics.transitions"))
+	assert.Equal(rejected+1, breakerCount("test-metrics.rejected"))
+	assert.NotNil(expvar.Get("circuit_breakers"))
+}
+
+// blockingClient answers 200 once release is closed
+type blockingClient struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func (b *blockingClient) Do(req *http.Request) (*http.Response, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
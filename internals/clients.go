@@ -2,6 +2,16 @@ package internals
 
 import "net/http"
 
+// HTTPClient is the client used to reach upstream services. Requests carry
+// their own context, so calls are cancelled together with the caller.
 type HTTPClient interface {
-	Get(url string) (resp *http.Response, err error)
+	Do(req *http.Request) (resp *http.Response, err error)
+}
+
+// ClientFactory creates the HTTPClient used to reach the named upstream
+type ClientFactory func(name string) HTTPClient
+
+// DefaultClient is a ClientFactory returning a plain http.Client
+func DefaultClient(name string) HTTPClient {
+	return &http.Client{}
 }
@@ -0,0 +1,311 @@
+// Package config loads the service configuration from the environment, a
+// .env file and an optional YAML or JSON file, validating it at startup.
+//
+// Each variable is taken from the first source that sets it, in that order,
+// falling back to its default.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+const (
+	// FileVariable names the optional YAML or JSON config file
+	FileVariable = "CONFIG_FILE"
+	DotEnvFile   = ".env"
+
+	Cache_Size     = 10000
+	Batch_Workers  = 8
+	Batch_MaxItems = 500
+	Stream_Workers = 8
+)
+
+// Config is the configuration of the service
+type Config struct {
+	Server          internals.ServerSettings
+	WeatherProvider string
+	WeatherAPIKey   string
+	CEPProviders    []string
+	CEPStrategy     string
+	Cache           CacheSettings
+	Breaker         internals.BreakerSettings
+	Retry           internals.RetrySettings
+	BatchWorkers    int
+	BatchMaxItems   int
+	StreamWorkers   int
+
+	variables []Variable
+}
+
+// CacheSettings configures the caches of the services
+type CacheSettings struct {
+	Size           int
+	RedisAddr      string
+	RedisPassword  string
+	RedisDB        int
+	RedisPrefix    string
+	CEPTTL         time.Duration
+	CEPNotFoundTTL time.Duration
+	WeatherTTL     time.Duration
+}
+
+// Variable is the effective value of a configuration variable and the source
+// it was taken from
+type Variable struct {
+	Name   string
+	Value  string
+	Source string
+	Secret bool
+}
+
+// Error lists every problem found in the configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load reads the configuration from the environment, the .env file when it
+// exists and the file named by CONFIG_FILE when set
+func Load() (*Config, error) {
+	sources := []Source{EnvSource()}
+	dotEnv, err := DotEnvSource(DotEnvFile)
+	if err == nil {
+		sources = append(sources, dotEnv)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading %s: %w", DotEnvFile, err)
+	}
+	for _, source := range sources {
+		if path := source.Values[FileVariable]; path != "" {
+			file, err := FileSource(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading config file: %w", err)
+			}
+			sources = append(sources, file)
+			break
+		}
+	}
+	return LoadFrom(sources...)
+}
+
+// LoadFrom reads the configuration from sources, the first one setting a
+// variable taking precedence, returning an *Error when it is not valid
+func LoadFrom(sources ...Source) (*Config, error) {
+	l := &loader{sources: sources}
+	config := &Config{
+		Server: internals.ServerSettings{
+			Port:              l.int("PORT", internals.Server_Port),
+			ReadHeaderTimeout: l.duration("SERVER_READ_HEADER_TIMEOUT", internals.Server_ReadHeaderTimeout),
+			ReadTimeout:       l.duration("SERVER_READ_TIMEOUT", internals.Server_ReadTimeout),
+			WriteTimeout:      l.duration("SERVER_WRITE_TIMEOUT", internals.Server_WriteTimeout),
+			IdleTimeout:       l.duration("SERVER_IDLE_TIMEOUT", internals.Server_IdleTimeout),
+			MaxHeaderBytes:    l.int("SERVER_MAX_HEADER_BYTES", internals.Server_MaxHeaderBytes),
+			ShutdownTimeout:   l.duration("SHUTDOWN_GRACE_PERIOD", internals.Server_ShutdownTimeout),
+		},
+		WeatherProvider: strings.ToLower(l.string("WEATHER_PROVIDER", services.WeatherProviderWeatherAPI)),
+		WeatherAPIKey:   l.secret("WEATHER_API_KEY"),
+		CEPProviders:    l.list("CEP_PROVIDERS", services.DefaultCEPProviders),
+		CEPStrategy:     strings.ToLower(l.string("CEP_STRATEGY", services.CEPStrategyFallback)),
+		Cache: CacheSettings{
+			Size:           l.int("CACHE_SIZE", Cache_Size),
+			RedisAddr:      l.string("REDIS_ADDR", ""),
+			RedisPassword:  l.secret("REDIS_PASSWORD"),
+			RedisDB:        l.int("REDIS_DB", 0),
+			RedisPrefix:    l.string("REDIS_PREFIX", ""),
+			CEPTTL:         l.duration("CEP_CACHE_TTL", services.CEPCache_TTL),
+			CEPNotFoundTTL: l.duration("CEP_CACHE_NOT_FOUND_TTL", services.CEPCache_NotFoundTTL),
+			WeatherTTL:     l.duration("WEATHER_CACHE_TTL", services.WeatherCache_TTL),
+		},
+		Breaker: internals.BreakerSettings{
+			FailureThreshold: l.int("CIRCUIT_FAILURE_THRESHOLD", internals.CircuitBreaker_FailureThreshold),
+			OpenTimeout:      l.duration("CIRCUIT_OPEN_TIMEOUT", internals.CircuitBreaker_OpenTimeout),
+			HalfOpenRequests: l.int("CIRCUIT_HALF_OPEN_REQUESTS", internals.CircuitBreaker_HalfOpenRequests),
+		},
+		Retry: internals.RetrySettings{
+			MaxAttempts: l.int("RETRY_MAX_ATTEMPTS", internals.Retry_MaxAttempts),
+			BaseDelay:   l.duration("RETRY_BASE_DELAY", internals.Retry_BaseDelay),
+			MaxDelay:    l.duration("RETRY_MAX_DELAY", internals.Retry_MaxDelay),
+		},
+		BatchWorkers:  l.int("BATCH_WORKERS", Batch_Workers),
+		BatchMaxItems: l.int("BATCH_MAX_ITEMS", Batch_MaxItems),
+		StreamWorkers: l.int("STREAM_WORKERS", Stream_Workers),
+	}
+	l.string(FileVariable, "")
+	l.checkUnknown()
+	config.validate(l)
+	if len(l.problems) > 0 {
+		return nil, &Error{Problems: l.problems}
+	}
+	config.variables = l.variables
+	return config, nil
+}
+
+// validate reports the values out of their range to l
+func (c *Config) validate(l *loader) {
+	l.check(c.Server.Port >= 1 && c.Server.Port <= 65535, "PORT must be from 1 to 65535, got %d", c.Server.Port)
+	l.check(c.Server.ReadHeaderTimeout >= 0, "SERVER_READ_HEADER_TIMEOUT must not be negative, got %s", c.Server.ReadHeaderTimeout)
+	l.check(c.Server.ReadTimeout >= 0, "SERVER_READ_TIMEOUT must not be negative, got %s", c.Server.ReadTimeout)
+	l.check(c.Server.WriteTimeout >= 0, "SERVER_WRITE_TIMEOUT must not be negative, got %s", c.Server.WriteTimeout)
+	l.check(c.Server.IdleTimeout >= 0, "SERVER_IDLE_TIMEOUT must not be negative, got %s", c.Server.IdleTimeout)
+	l.check(c.Server.MaxHeaderBytes >= 1, "SERVER_MAX_HEADER_BYTES must be at least 1, got %d", c.Server.MaxHeaderBytes)
+	l.check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_GRACE_PERIOD must be positive, got %s", c.Server.ShutdownTimeout)
+
+	weatherProviders := []string{services.WeatherProviderWeatherAPI, services.WeatherProviderOpenMeteo}
+	l.check(slices.Contains(weatherProviders, c.WeatherProvider), "WEATHER_PROVIDER must be one of %s, got %q", strings.Join(weatherProviders, ", "), c.WeatherProvider)
+	l.check(c.WeatherProvider != services.WeatherProviderWeatherAPI || c.WeatherAPIKey != "", "WEATHER_API_KEY is required by the %s provider", services.WeatherProviderWeatherAPI)
+	cepProviders := []string{services.ProviderViaCEP, services.ProviderBrasilAPI, services.ProviderOpenCEP, services.ProviderPostmon}
+	for _, provider := range c.CEPProviders {
+		l.check(slices.Contains(cepProviders, provider), "CEP_PROVIDERS must only have %s, got %q", strings.Join(cepProviders, ", "), provider)
+	}
+	cepStrategies := []string{services.CEPStrategyFallback, services.CEPStrategyRace}
+	l.check(slices.Contains(cepStrategies, c.CEPStrategy), "CEP_STRATEGY must be one of %s, got %q", strings.Join(cepStrategies, ", "), c.CEPStrategy)
+
+	l.check(c.Cache.Size >= 1, "CACHE_SIZE must be at least 1, got %d", c.Cache.Size)
+	l.check(c.Cache.RedisDB >= 0, "REDIS_DB must not be negative, got %d", c.Cache.RedisDB)
+	l.check(c.Cache.CEPTTL > 0, "CEP_CACHE_TTL must be positive, got %s", c.Cache.CEPTTL)
+	l.check(c.Cache.CEPNotFoundTTL > 0, "CEP_CACHE_NOT_FOUND_TTL must be positive, got %s", c.Cache.CEPNotFoundTTL)
+	l.check(c.Cache.WeatherTTL > 0, "WEATHER_CACHE_TTL must be positive, got %s", c.Cache.WeatherTTL)
+
+	l.check(c.Breaker.FailureThreshold >= 1, "CIRCUIT_FAILURE_THRESHOLD must be at least 1, got %d", c.Breaker.FailureThreshold)
+	l.check(c.Breaker.OpenTimeout > 0, "CIRCUIT_OPEN_TIMEOUT must be positive, got %s", c.Breaker.OpenTimeout)
+	l.check(c.Breaker.HalfOpenRequests >= 1, "CIRCUIT_HALF_OPEN_REQUESTS must be at least 1, got %d", c.Breaker.HalfOpenRequests)
+	l.check(c.Retry.MaxAttempts >= 1, "RETRY_MAX_ATTEMPTS must be at least 1, got %d", c.Retry.MaxAttempts)
+	l.check(c.Retry.BaseDelay >= 0, "RETRY_BASE_DELAY must not be negative, got %s", c.Retry.BaseDelay)
+	l.check(c.Retry.MaxDelay >= c.Retry.BaseDelay, "RETRY_MAX_DELAY must not be shorter than RETRY_BASE_DELAY, got %s", c.Retry.MaxDelay)
+
+	l.check(c.BatchWorkers >= 1, "BATCH_WORKERS must be at least 1, got %d", c.BatchWorkers)
+	l.check(c.BatchMaxItems >= 1, "BATCH_MAX_ITEMS must be at least 1, got %d", c.BatchMaxItems)
+	l.check(c.StreamWorkers >= 1, "STREAM_WORKERS must be at least 1, got %d", c.StreamWorkers)
+}
+
+// Summary returns the effective configuration, one "NAME=value (source)"
+// line per variable, with the value of secrets redacted
+func (c *Config) Summary() string {
+	var summary strings.Builder
+	for _, variable := range c.variables {
+		value := variable.Value
+		if variable.Secret && value != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&summary, "%s=%s (%s)\n", variable.Name, value, variable.Source)
+	}
+	return summary.String()
+}
+
+// loader reads the variables from its sources, recording their effective
+// values and the problems found
+type loader struct {
+	sources   []Source
+	variables []Variable
+	problems  []string
+}
+
+// lookup returns the value of the first source setting name and its name
+func (l *loader) lookup(name string) (string, string, bool) {
+	for _, source := range l.sources {
+		if value, ok := source.Values[name]; ok {
+			return strings.TrimSpace(value), source.Name, true
+		}
+	}
+	return "", "default", false
+}
+
+func (l *loader) record(name, value, source string, secret bool) {
+	l.variables = append(l.variables, Variable{Name: name, Value: value, Source: source, Secret: secret})
+}
+
+func (l *loader) check(ok bool, format string, args ...any) {
+	if !ok {
+		l.problems = append(l.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *loader) string(name, fallback string) string {
+	value, source, ok := l.lookup(name)
+	if !ok {
+		value = fallback
+	}
+	l.record(name, value, source, false)
+	return value
+}
+
+func (l *loader) secret(name string) string {
+	value, source, _ := l.lookup(name)
+	l.record(name, value, source, true)
+	return value
+}
+
+func (l *loader) int(name string, fallback int) int {
+	text, source, ok := l.lookup(name)
+	value := fallback
+	if ok && text != "" {
+		parsed, err := strconv.Atoi(text)
+		l.check(err == nil, "%s must be an integer, got %q from %s", name, text, source)
+		if err == nil {
+			value = parsed
+		}
+	}
+	l.record(name, strconv.Itoa(value), source, false)
+	return value
+}
+
+func (l *loader) duration(name string, fallback time.Duration) time.Duration {
+	text, source, ok := l.lookup(name)
+	value := fallback
+	if ok && text != "" {
+		parsed, err := time.ParseDuration(text)
+		l.check(err == nil, "%s must be a duration such as 30s or 15m, got %q from %s", name, text, source)
+		if err == nil {
+			value = parsed
+		}
+	}
+	l.record(name, value.String(), source, false)
+	return value
+}
+
+// list reads a comma separated value, ignoring empty items, using fallback
+// when it has none
+func (l *loader) list(name string, fallback []string) []string {
+	text, source, _ := l.lookup(name)
+	var values []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			values = append(values, item)
+		}
+	}
+	if len(values) == 0 {
+		values = fallback
+	}
+	l.record(name, strings.Join(values, ","), source, false)
+	return values
+}
+
+// checkUnknown reports the variables of strict sources that were not read
+func (l *loader) checkUnknown() {
+	for _, source := range l.sources {
+		if !source.Strict {
+			continue
+		}
+		var unknown []string
+		for name := range source.Values {
+			if !slices.ContainsFunc(l.variables, func(v Variable) bool { return v.Name == name }) {
+				unknown = append(unknown, name)
+			}
+		}
+		slices.Sort(unknown)
+		for _, name := range unknown {
+			l.problems = append(l.problems, fmt.Sprintf("%s: unknown variable %s", source.Name, name))
+		}
+	}
+}
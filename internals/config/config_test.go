@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFromDefaults(t *testing.T) {
+	config, err := LoadFrom(Source{Name: "env", Values: map[string]string{"WEATHER_API_KEY": "key"}})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(internals.DefaultServerSettings(), config.Server)
+	assert.Equal(internals.DefaultBreakerSettings(), config.Breaker)
+	assert.Equal(internals.DefaultRetrySettings(), config.Retry)
+	assert.Equal(services.WeatherProviderWeatherAPI, config.WeatherProvider)
+	assert.Equal(services.DefaultCEPProviders, config.CEPProviders)
+	assert.Equal(services.CEPStrategyFallback, config.CEPStrategy)
+	assert.Equal(Cache_Size, config.Cache.Size)
+	assert.Equal(services.CEPCache_TTL, config.Cache.CEPTTL)
+	assert.Equal(Batch_Workers, config.BatchWorkers)
+	assert.Equal(Batch_MaxItems, config.BatchMaxItems)
+	assert.Equal(Stream_Workers, config.StreamWorkers)
+}
+
+func TestLoadFromPrecedence(t *testing.T) {
+	config, err := LoadFrom(
+		Source{Name: "env", Values: map[string]string{"PORT": "9000", "CEP_PROVIDERS": "BrasilAPI, ,viacep"}},
+		Source{Name: ".env", Values: map[string]string{"PORT": "9001", "WEATHER_PROVIDER": "openmeteo", "RETRY_BASE_DELAY": "50ms"}},
+		Source{Name: "config.yaml", Values: map[string]string{"WEATHER_PROVIDER": "weatherapi", "CACHE_SIZE": "10"}, Strict: true},
+	)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(9000, config.Server.Port)
+	assert.Equal([]string{"brasilapi", "viacep"}, config.CEPProviders)
+	assert.Equal(services.WeatherProviderOpenMeteo, config.WeatherProvider)
+	assert.Equal(50*time.Millisecond, config.Retry.BaseDelay)
+	assert.Equal(10, config.Cache.Size)
+}
+
+func TestLoadFromReportsEveryProblem(t *testing.T) {
+	_, err := LoadFrom(
+		Source{Name: "env", Values: map[string]string{
+			"PORT":                 "70000",
+			"CACHE_SIZE":           "many",
+			"CIRCUIT_OPEN_TIMEOUT": "10",
+			"CEP_PROVIDERS":        "viacep,correios",
+			"CEP_STRATEGY":         "fastest",
+			"RETRY_BASE_DELAY":     "2s",
+			"RETRY_MAX_DELAY":      "1s",
+			"BATCH_WORKERS":        "0",
+		}},
+		Source{Name: "config.json", Values: map[string]string{"PROT": "8080"}, Strict: true},
+	)
+
+	var configErr *Error
+	assert := assert.New(t)
+	assert.True(errors.As(err, &configErr))
+	assert.Equal([]string{
+		`CACHE_SIZE must be an integer, got "many" from env`,
+		`CIRCUIT_OPEN_TIMEOUT must be a duration such as 30s or 15m, got "10" from env`,
+		`config.json: unknown variable PROT`,
+		`PORT must be from 1 to 65535, got 70000`,
+		`WEATHER_API_KEY is required by the weatherapi provider`,
+		`CEP_PROVIDERS must only have viacep, brasilapi, opencep, postmon, got "correios"`,
+		`CEP_STRATEGY must be one of fallback, race, got "fastest"`,
+		`RETRY_MAX_DELAY must not be shorter than RETRY_BASE_DELAY, got 1s`,
+		`BATCH_WORKERS must be at least 1, got 0`,
+	}, configErr.Problems)
+	assert.Contains(err.Error(), "invalid configuration:\n  - CACHE_SIZE")
+}
+
+func TestSummaryRedactsSecrets(t *testing.T) {
+	config, err := LoadFrom(Source{Name: "env", Values: map[string]string{
+		"WEATHER_API_KEY": "super-secret",
+		"REDIS_ADDR":      "localhost:6379",
+	}})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	summary := config.Summary()
+	assert.NotContains(summary, "super-secret")
+	assert.Contains(summary, "WEATHER_API_KEY=[redacted] (env)\n")
+	assert.Contains(summary, "REDIS_PASSWORD= (default)\n")
+	assert.Contains(summary, "REDIS_ADDR=localhost:6379 (env)\n")
+	assert.Contains(summary, "PORT=8080 (default)\n")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	t.Cleanup(func() { os.Chdir(wd) })
+	os.WriteFile(DotEnvFile, []byte("WEATHER_API_KEY=key\nPORT=9001\nCONFIG_FILE=config.yaml\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("port: 9002\ncache_size: 10\n"), 0o600)
+	t.Setenv("BATCH_WORKERS", "4")
+
+	config, err := Load()
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(9001, config.Server.Port)
+	assert.Equal(10, config.Cache.Size)
+	assert.Equal(4, config.BatchWorkers)
+	assert.Empty(os.Getenv("WEATHER_API_KEY"))
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Source holds configuration variables by name, such as PORT
+type Source struct {
+	// Name identifies the source in problems and in the summary
+	Name   string
+	Values map[string]string
+	// Strict sources report their unknown variables as problems
+	Strict bool
+}
+
+// EnvSource returns the variables of the process environment
+func EnvSource() Source {
+	values := map[string]string{}
+	for _, variable := range os.Environ() {
+		if name, value, ok := strings.Cut(variable, "="); ok {
+			values[name] = value
+		}
+	}
+	return Source{Name: "env", Values: values}
+}
+
+// DotEnvSource returns the variables of a .env file, without setting them in
+// the process environment
+func DotEnvSource(path string) (Source, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Name: path, Values: values}, nil
+}
+
+// FileSource returns the variables of a YAML or JSON file, picked by its
+// extension. Its keys are the variable names, in any case, such as
+// port: 8080 or "CEP_PROVIDERS": ["viacep", "brasilapi"], lists being
+// joined with commas
+func FileSource(path string) (Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Source{}, err
+	}
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return Source{}, fmt.Errorf("%s: unknown config file format, expected .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return Source{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		text, err := fileValue(value)
+		if err != nil {
+			return Source{}, fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		values[strings.ToUpper(key)] = text
+	}
+	return Source{Name: path, Values: values, Strict: true}, nil
+}
+
+// fileValue returns the text of a scalar or a list of scalars of a file
+func fileValue(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case map[string]any:
+		return "", fmt.Errorf("nested values are not supported")
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			text, err := fileValue(item)
+			if err != nil {
+				return "", err
+			} else if _, ok := item.([]any); ok {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": "port: 9000\nweather_provider: openmeteo\ncep_providers:\n  - viacep\n  - brasilapi\nredis_addr:\n",
+		"config.json": `{"PORT": 9000, "weather_provider": "openmeteo", "cep_providers": ["viacep", "brasilapi"], "redis_addr": null}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o600)
+
+		source, err := FileSource(path)
+
+		assert := assert.New(t)
+		assert.Nil(err, name)
+		assert.True(source.Strict, name)
+		assert.Equal(map[string]string{
+			"PORT":             "9000",
+			"WEATHER_PROVIDER": "openmeteo",
+			"CEP_PROVIDERS":    "viacep,brasilapi",
+			"REDIS_ADDR":       "",
+		}, source.Values, name)
+	}
+}
+
+func TestFileSourceErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.toml":   "port = 9000",
+		"invalid.json":  `{"port": `,
+		"nested.yaml":   "server:\n  port: 9000\n",
+		"lists.json":    `{"cep_providers": [["viacep"]]}`,
+		"sequence.yaml": "- port\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o600)
+
+		_, err := FileSource(path)
+
+		assert.Error(t, err, name)
+	}
+	_, err := FileSource(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDotEnvSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("# comment\nDOTENV_SOURCE_TEST=value\n"), 0o600)
+
+	source, err := DotEnvSource(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DOTENV_SOURCE_TEST": "value"}, source.Values)
+	assert.Empty(t, os.Getenv("DOTENV_SOURCE_TEST"))
+}
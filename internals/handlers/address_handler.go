@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/rcbadiale/go-cloud-run/internals/ibge"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+const CEPSearch_MinLength = 3
+
+// AddressHandler serves the address lookups
+type AddressHandler struct {
+	CEPService       services.CEPService
+	CEPSearchService services.CEPSearchService
+	Geocoder         services.Geocoder
+}
+
+// GetAddressResponse is the normalized address of a CEP, IBGE code, DDD and
+// coordinates being omitted when unknown
+type GetAddressResponse struct {
+	Cep          string                `json:"cep"`
+	Street       string                `json:"street"`
+	Complement   string                `json:"complement,omitempty"`
+	Neighborhood string                `json:"neighborhood"`
+	City         string                `json:"city"`
+	UF           string                `json:"uf"`
+	State        string                `json:"state"`
+	Ibge         string                `json:"ibge,omitempty"`
+	Ddd          string                `json:"ddd,omitempty"`
+	Coordinates  *services.Coordinates `json:"coordinates,omitempty"`
+	Provider     string                `json:"provider"`
+}
+
+type SearchCEPResponse struct {
+	Results []AddressResponse `json:"results"`
+}
+
+// NewAddressHandler creates a new AddressHandler
+func NewAddressHandler(cepService services.CEPService, searchService services.CEPSearchService, geocoder services.Geocoder) *AddressHandler {
+	return &AddressHandler{
+		CEPService:       cepService,
+		CEPSearchService: searchService,
+		Geocoder:         geocoder,
+	}
+}
+
+// GetAddress returns the address of a CEP, validated and cached as the
+// weather lookups do
+func (ah *AddressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	address, ok := resolveAddress(w, r, ah.CEPService)
+	if !ok {
+		return
+	}
+	response := newGetAddressResponse(address)
+	if response.Coordinates == nil && ah.Geocoder != nil {
+		coordinates, err := ah.Geocoder.Geocode(r.Context(), address)
+		if err != nil {
+			log.Printf("error geocoding %s: %v\n", address.WeatherQuery(), err)
+		}
+		response.Coordinates = coordinates
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// newGetAddressResponse normalizes address: the CEP is formatted as
+// 00000-000, the UF is upper case along with its state name, and a missing
+// IBGE code is looked up in the IBGE municipality table
+func newGetAddressResponse(address *services.Address) GetAddressResponse {
+	response := GetAddressResponse{
+		Cep:          address.Cep,
+		Street:       address.Street,
+		Complement:   address.Complement,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		UF:           strings.ToUpper(strings.TrimSpace(address.State)),
+		Ibge:         address.Ibge,
+		Ddd:          address.Ddd,
+		Coordinates:  address.Coordinates,
+		Provider:     address.Provider,
+	}
+	if zipCode, err := cep.Parse(address.Cep); err == nil {
+		response.Cep = zipCode.String()
+	}
+	response.State = services.StateNames[response.UF]
+	if response.Ibge == "" {
+		if municipality, ok := ibge.FindByName(response.UF, response.City); ok {
+			response.Ibge = municipality.Code
+		}
+	}
+	return response
+}
+
+// SearchCEP returns the CEPs, along with their addresses, of the street in
+// the city and UF of the request
+func (ah *AddressHandler) SearchCEP(w http.ResponseWriter, r *http.Request) {
+	if ah.CEPSearchService == nil {
+		writeProblem(w, r, errNotImplemented, "none of the configured CEP providers supports searching CEPs")
+		return
+	}
+	query, err := parseAddressQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	addresses, err := ah.CEPSearchService.SearchCEP(r.Context(), query)
+	if errors.Is(err, services.ErrCEPNotFound) {
+		writeProblem(w, r, errCEPNotFound, "no CEP was found for the address")
+		return
+	} else if errors.Is(err, services.ErrInvalidCEP) {
+		writeProblem(w, r, errInvalidParameter, "the address was rejected by the address provider")
+		return
+	} else if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	response := SearchCEPResponse{Results: make([]AddressResponse, 0, len(addresses))}
+	for _, address := range addresses {
+		response.Results = append(response.Results, newAddressResponse(&address))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseAddressQuery parses the uf, city and street query parameters
+func parseAddressQuery(query url.Values) (services.AddressQuery, error) {
+	search := services.AddressQuery{
+		UF:     strings.ToUpper(strings.TrimSpace(query.Get("uf"))),
+		City:   strings.TrimSpace(query.Get("city")),
+		Street: strings.TrimSpace(query.Get("street")),
+	}
+	if _, ok := services.StateNames[search.UF]; !ok {
+		return services.AddressQuery{}, fmt.Errorf("unknown uf %q, expected the 2 letter code of a state", query.Get("uf"))
+	}
+	if utf8.RuneCountInString(search.City) < CEPSearch_MinLength {
+		return services.AddressQuery{}, fmt.Errorf("city must have at least %d characters", CEPSearch_MinLength)
+	}
+	if utf8.RuneCountInString(search.Street) < CEPSearch_MinLength {
+		return services.AddressQuery{}, fmt.Errorf("street must have at least %d characters", CEPSearch_MinLength)
+	}
+	return search, nil
+}
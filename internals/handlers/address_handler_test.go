@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCEPSearchService struct {
+	mock.Mock
+}
+
+func (m *MockCEPSearchService) SearchCEP(ctx context.Context, query services.AddressQuery) ([]services.Address, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]services.Address), args.Error(1)
+}
+
+func TestGetAddress(t *testing.T) {
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "01001000").Return(&services.Address{
+		Cep: "01001-000", Street: "Praça da Sé", Complement: "lado ímpar", Neighborhood: "Sé", City: "São Paulo", State: "SP",
+		Ibge: "3550308", Ddd: "11", Coordinates: &services.Coordinates{Lat: -23.5507, Lon: -46.6339}, Provider: services.ProviderViaCEP,
+	}, nil)
+	mockGeocoder := new(MockGeocoder)
+
+	handler := NewAddressHandler(mockCEPService, nil, mockGeocoder)
+	rr := serve(t, "GET", "/address/{zipCode}", handler.GetAddress, "/address/01001-000", "")
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Equal("application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(`{
+		"cep": "01001-000", "street": "Praça da Sé", "complement": "lado ímpar", "neighborhood": "Sé", "city": "São Paulo",
+		"uf": "SP", "state": "São Paulo", "ibge": "3550308", "ddd": "11", "coordinates": {"lat": -23.5507, "lon": -46.6339}, "provider": "viacep"
+	}`, rr.Body.String())
+	mockGeocoder.AssertNotCalled(t, "Geocode", mock.Anything, mock.Anything)
+}
+
+func TestGetAddressNormalizes(t *testing.T) {
+	address := &services.Address{Cep: "88010000", Street: "Rua Felipe Schmidt", City: "Florianópolis", State: "sc", Provider: services.ProviderPostmon}
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(address, nil)
+	mockGeocoder := new(MockGeocoder)
+	mockGeocoder.On("Geocode", mock.Anything, address).Return(&services.Coordinates{Lat: -27.5945, Lon: -48.5477}, nil)
+
+	handler := NewAddressHandler(mockCEPService, nil, mockGeocoder)
+	rr := serve(t, "GET", "/address/{zipCode}", handler.GetAddress, "/address/88010000", "")
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	var response GetAddressResponse
+	assert.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(GetAddressResponse{
+		Cep: "88010-000", Street: "Rua Felipe Schmidt", City: "Florianópolis", UF: "SC", State: "Santa Catarina",
+		Ibge: "4205407", Coordinates: &services.Coordinates{Lat: -27.5945, Lon: -48.5477}, Provider: services.ProviderPostmon,
+	}, response)
+}
+
+func TestGetAddressErrors(t *testing.T) {
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "99999999").Return((*services.Address)(nil), services.ErrCEPNotFound)
+	handler := NewAddressHandler(mockCEPService, nil, nil)
+
+	rr := serve(t, "GET", "/address/{zipCode}", handler.GetAddress, "/address/99999999", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"cep_not_found"`)
+
+	rr = serve(t, "GET", "/address/{zipCode}", handler.GetAddress, "/address/abcdefgh", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"invalid_cep"`)
+	mockCEPService.AssertNumberOfCalls(t, "GetAddressByCEP", 1)
+}
+
+func TestSearchCEP(t *testing.T) {
+	mockSearchService := new(MockCEPSearchService)
+	mockSearchService.On("SearchCEP", mock.Anything, services.AddressQuery{UF: "SP", City: "São Paulo", Street: "Praça da Sé"}).Return([]services.Address{
+		{Cep: "01001-000", Street: "Praça da Sé", Complement: "lado ímpar", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+		{Cep: "01001-001", Street: "Praça da Sé", Complement: "lado par", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+	}, nil)
+
+	handler := NewAddressHandler(nil, mockSearchService, nil)
+	rr := serve(t, "GET", "/cep/search", handler.SearchCEP, "/cep/search?uf=sp&city=S%C3%A3o+Paulo&street=Pra%C3%A7a+da+S%C3%A9", "")
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	var response SearchCEPResponse
+	assert.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal([]AddressResponse{
+		{Cep: "01001-000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+		{Cep: "01001-001", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+	}, response.Results)
+}
+
+func TestSearchCEPInvalidParameters(t *testing.T) {
+	mockSearchService := new(MockCEPSearchService)
+	for path, detail := range map[string]string{
+		"/cep/search?city=Campinas&street=Rua+Um":              `unknown uf \"\"`,
+		"/cep/search?uf=XX&city=Campinas&street=Rua+Um":        `unknown uf \"XX\"`,
+		"/cep/search?uf=SP&city=Ca&street=Rua+Um":              "city must have at least 3 characters",
+		"/cep/search?uf=SP&city=Campinas&street=+Ru+":          "street must have at least 3 characters",
+		"/cep/search?uf=SP&city=S%C3%A3o+Paulo&street=S%C3%A9": "street must have at least 3 characters",
+	} {
+		handler := NewAddressHandler(nil, mockSearchService, nil)
+		rr := serve(t, "GET", "/cep/search", handler.SearchCEP, path, "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, path)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_parameter"`, path)
+		assert.Contains(t, rr.Body.String(), detail, path)
+	}
+	mockSearchService.AssertNotCalled(t, "SearchCEP", mock.Anything, mock.Anything)
+}
+
+func TestSearchCEPErrors(t *testing.T) {
+	for _, test := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{services.ErrCEPNotFound, http.StatusNotFound, CodeCEPNotFound},
+		{services.ErrInvalidCEP, http.StatusBadRequest, CodeInvalidParameter},
+		{&services.UpstreamError{Service: services.ProviderViaCEP, Kind: services.ErrCEPServiceUnavailable, StatusCode: 503}, http.StatusServiceUnavailable, CodeUpstreamUnavailable},
+	} {
+		mockSearchService := new(MockCEPSearchService)
+		mockSearchService.On("SearchCEP", mock.Anything, mock.Anything).Return([]services.Address(nil), test.err)
+
+		handler := NewAddressHandler(nil, mockSearchService, nil)
+		rr := serve(t, "GET", "/cep/search", handler.SearchCEP, "/cep/search?uf=SP&city=Campinas&street=Rua+Um", "")
+
+		assert.Equal(t, test.status, rr.Code, test.code)
+		assert.Contains(t, rr.Body.String(), `"code":"`+test.code+`"`)
+	}
+}
+
+func TestSearchCEPNotImplemented(t *testing.T) {
+	handler := NewAddressHandler(nil, nil, nil)
+	rr := serve(t, "GET", "/cep/search", handler.SearchCEP, "/cep/search?uf=SP&city=Campinas&street=Rua+Um", "")
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
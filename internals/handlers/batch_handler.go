@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+const (
+	Batch_MaxItems     = 500
+	Batch_Workers      = 8
+	Batch_MaxBodyBytes = 1 << 20
+)
+
+type BatchWeatherResponse struct {
+	Results []BatchWeatherResult `json:"results"`
+}
+
+// BatchWeatherResult is the weather of a CEP of the batch, or the problem
+// that prevented looking it up
+type BatchWeatherResult struct {
+	Cep     string              `json:"cep"`
+	Weather *GetWeatherResponse `json:"weather,omitempty"`
+	Error   *Problem            `json:"error,omitempty"`
+}
+
+// batchAddress is the outcome of resolving a CEP of the batch
+type batchAddress struct {
+	cep         cep.CEP
+	address     *services.Address
+	coordinates *services.Coordinates
+	location    string
+	err         error
+}
+
+// batchWeather is the outcome of looking the weather of a location up
+type batchWeather struct {
+	weather *services.Weather
+	err     error
+}
+
+// GetWeatherBatch returns the weather for a JSON array of CEPs, resolving
+// each distinct CEP and location once
+func (wh *WeatherHandler) GetWeatherBatch(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	format, err := parseOutputFormat(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	var ceps []string
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, Batch_MaxBodyBytes)).Decode(&ceps)
+	if err != nil {
+		writeProblem(w, r, errInvalidBody, "the body must be a JSON array of CEPs")
+		return
+	}
+	maxItems := wh.BatchMaxItems
+	if maxItems <= 0 {
+		maxItems = Batch_MaxItems
+	}
+	if len(ceps) > maxItems {
+		writeProblem(w, r, errInvalidBody, fmt.Sprintf("the batch must not have more than %d CEPs", maxItems))
+		return
+	}
+
+	addresses := wh.resolveBatchAddresses(r.Context(), ceps)
+	weathers := wh.resolveBatchWeathers(r.Context(), addresses)
+
+	response := BatchWeatherResponse{Results: make([]BatchWeatherResult, 0, len(ceps))}
+	for _, cep := range ceps {
+		result := BatchWeatherResult{Cep: cep}
+		address := addresses[cep]
+		err := address.err
+		if err == nil {
+			weather := weathers[address.location]
+			if err = weather.err; err == nil {
+				output := newWeatherResponse(address.address, weather.weather, fields, format)
+				result.Weather = &output
+			}
+		}
+		if err != nil {
+			problem := batchProblem(err)
+			result.Error = &problem
+		}
+		response.Results = append(response.Results, result)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// resolveBatchAddresses resolves each distinct CEP once, along with the
+// location its weather is looked up by, keyed by the CEPs as written in the
+// batch. CEPs written differently, such as 01001000 and 01001-000, share a
+// lookup
+func (wh *WeatherHandler) resolveBatchAddresses(ctx context.Context, ceps []string) map[string]*batchAddress {
+	addresses := map[string]*batchAddress{}
+	byCEP := map[cep.CEP]*batchAddress{}
+	var distinct []*batchAddress
+	for _, value := range ceps {
+		if _, ok := addresses[value]; ok {
+			continue
+		}
+		zipCode, err := cep.Parse(value)
+		if err != nil {
+			addresses[value] = &batchAddress{err: err}
+		} else if address, ok := byCEP[zipCode]; ok {
+			addresses[value] = address
+		} else {
+			address := &batchAddress{cep: zipCode}
+			byCEP[zipCode] = address
+			addresses[value] = address
+			distinct = append(distinct, address)
+		}
+	}
+	wh.runBatch(len(distinct), func(i int) {
+		result := distinct[i]
+		result.address, result.err = wh.CEPService.GetAddressByCEP(ctx, result.cep)
+		if result.err == nil {
+			result.coordinates = wh.coordinatesFor(ctx, result.address)
+			result.location = locationKey(result.address, result.coordinates)
+		}
+	})
+	return addresses
+}
+
+// resolveBatchWeathers looks the weather of each distinct location up once
+func (wh *WeatherHandler) resolveBatchWeathers(ctx context.Context, addresses map[string]*batchAddress) map[string]*batchWeather {
+	weathers := map[string]*batchWeather{}
+	var locations []*batchAddress
+	for _, address := range addresses {
+		if _, ok := weathers[address.location]; !ok && address.err == nil {
+			weathers[address.location] = &batchWeather{}
+			locations = append(locations, address)
+		}
+	}
+	wh.runBatch(len(locations), func(i int) {
+		address := locations[i]
+		result := weathers[address.location]
+		result.weather, result.err = wh.weatherAt(ctx, address.address, address.coordinates)
+	})
+	return weathers
+}
+
+// locationKey returns the key of the location the weather of address is
+// looked up by, matching addresses in the same place
+func locationKey(address *services.Address, coordinates *services.Coordinates) string {
+	if coordinates != nil {
+		return fmt.Sprintf("coords:%f,%f", coordinates.Lat, coordinates.Lon)
+	}
+	return "city:" + strings.ToLower(address.WeatherQuery())
+}
+
+// runBatch calls work for every index up to count, on at most BatchWorkers
+// goroutines at a time
+func (wh *WeatherHandler) runBatch(count int, work func(i int)) {
+	workers := wh.BatchWorkers
+	if workers <= 0 {
+		workers = Batch_Workers
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, count) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				work(i)
+			}
+		}()
+	}
+	for i := range count {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// batchProblem returns the Problem reported for a CEP of the batch
+func batchProblem(err error) Problem {
+	var cepErr cep.Error
+	if errors.As(err, &cepErr) {
+		return newProblem(errInvalidCEP, cepErr.Error())
+	}
+	return newProblem(serviceError(err))
+}
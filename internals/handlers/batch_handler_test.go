@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// concurrentCEPService resolves every CEP to the city in cities, tracking
+// how many lookups run at the same time
+type concurrentCEPService struct {
+	cities  map[string]string
+	mu      sync.Mutex
+	calls   map[string]int
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (c *concurrentCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*services.Address, error) {
+	running := c.running.Add(1)
+	defer c.running.Add(-1)
+	for peak := c.peak.Load(); running > peak && !c.peak.CompareAndSwap(peak, running); peak = c.peak.Load() {
+	}
+	c.mu.Lock()
+	c.calls[cep.Digits()]++
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+
+	city, ok := c.cities[cep.Digits()]
+	if !ok {
+		return nil, services.ErrCEPNotFound
+	}
+	return &services.Address{Cep: cep.String(), City: city, State: "SP"}, nil
+}
+
+func TestGetWeatherBatch(t *testing.T) {
+	cepService := &concurrentCEPService{
+		cities: map[string]string{"01001000": "São Paulo", "01310100": "São Paulo", "13405162": "Piracicaba"},
+		calls:  map[string]int{},
+	}
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, "São Paulo, São Paulo, Brazil").Return(&services.Weather{Current: services.CurrentWeather{TempC: 20, TempF: 68}}, nil)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, "Piracicaba, São Paulo, Brazil").Return(
+		(*services.Weather)(nil), &services.UpstreamError{Service: "weatherapi", Kind: services.ErrLocationNotFound, Code: 1006},
+	)
+	handler := &WeatherHandler{CEPService: cepService, WeatherService: mockWeatherService}
+
+	rr := serve(t, "POST", "/weather/batch", handler.GetWeatherBatch, "/weather/batch?units=metric", `["01001000", "01310100", "01001-000", "13405162", "99999999", "123"]`)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	var response BatchWeatherResponse
+	assert.NoError(json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(response.Results, 6)
+	for i, cep := range []string{"01001000", "01310100", "01001-000"} {
+		assert.Equal(cep, response.Results[i].Cep)
+		assert.Nil(response.Results[i].Error)
+		assert.Equal(float64(20), *response.Results[i].Weather.TempC)
+		assert.Nil(response.Results[i].Weather.TempF)
+	}
+	assert.Equal(CodeWeatherNotFound, response.Results[3].Error.Code)
+	assert.Equal(http.StatusNotFound, response.Results[3].Error.Status)
+	assert.Equal(CodeCEPNotFound, response.Results[4].Error.Code)
+	assert.Equal(CodeInvalidCEP, response.Results[5].Error.Code)
+	assert.Nil(response.Results[5].Weather)
+
+	assert.Equal(map[string]int{"01001000": 1, "01310100": 1, "13405162": 1, "99999999": 1}, cepService.calls)
+	mockWeatherService.AssertNumberOfCalls(t, "GetWeatherByCity", 2)
+}
+
+func TestGetWeatherBatchBoundsWorkers(t *testing.T) {
+	cepService := &concurrentCEPService{cities: map[string]string{}, calls: map[string]int{}}
+	handler := &WeatherHandler{CEPService: cepService, BatchWorkers: 3}
+	ceps := make([]string, 20)
+	for i := range ceps {
+		ceps[i] = fmt.Sprintf("%08d", 1000000+i)
+	}
+	body, _ := json.Marshal(ceps)
+
+	rr := serve(t, "POST", "/weather/batch", handler.GetWeatherBatch, "/weather/batch", string(body))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, cepService.calls, 20)
+	assert.LessOrEqual(t, cepService.peak.Load(), int32(3))
+	assert.Greater(t, cepService.peak.Load(), int32(1))
+}
+
+func TestGetWeatherBatchInvalidBody(t *testing.T) {
+	handler := &WeatherHandler{CEPService: new(MockViaCEPService), BatchMaxItems: 2}
+	for _, body := range []string{``, `{"ceps": []}`, `[1, 2]`, `["01001000", "01001000", "01001000"]`} {
+		rr := serve(t, "POST", "/weather/batch", handler.GetWeatherBatch, "/weather/batch", body)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_body"`, body)
+	}
+}
+
+func TestGetWeatherBatchEmpty(t *testing.T) {
+	handler := &WeatherHandler{}
+	rr := serve(t, "POST", "/weather/batch", handler.GetWeatherBatch, "/weather/batch", `[]`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"results":[]}`, strings.TrimRight(rr.Body.String(), "\n"))
+}
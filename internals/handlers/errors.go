@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	CodeInvalidCEP          = "invalid_cep"
+	CodeCEPNotFound         = "cep_not_found"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeWeatherNotFound     = "weather_not_found"
+	CodeUpstreamRateLimited = "upstream_rate_limited"
+	CodeUpstreamRejected    = "upstream_rejected"
+	CodeUpstreamMalformed   = "upstream_malformed_response"
+	CodeInternalError       = "internal_error"
+	CodeInvalidParameter    = "invalid_parameter"
+	CodeNotImplemented      = "not_implemented"
+	CodeInvalidBody         = "invalid_body"
+	CodeLocationNotFound    = "location_not_found"
+)
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// apiError describes how a failure is reported, both as a Problem and as
+// the legacy plain text body
+type apiError struct {
+	Status int
+	Code   string
+	Title  string
+	Text   string
+}
+
+var (
+	errInvalidCEP          = apiError{http.StatusUnprocessableEntity, CodeInvalidCEP, "Invalid CEP", InvalidZipCode}
+	errCEPNotFound         = apiError{http.StatusNotFound, CodeCEPNotFound, "CEP not found", CannotFindZipCode}
+	errUpstreamUnavailable = apiError{http.StatusServiceUnavailable, CodeUpstreamUnavailable, "Upstream service unavailable", ServiceUnavailable}
+	errWeatherNotFound     = apiError{http.StatusNotFound, CodeWeatherNotFound, "Weather not found", CannotFindWeather}
+	errUpstreamRateLimited = apiError{http.StatusServiceUnavailable, CodeUpstreamRateLimited, "Upstream rate limit exceeded", ServiceUnavailable}
+	errUpstreamRejected    = apiError{http.StatusBadGateway, CodeUpstreamRejected, "Upstream service rejected the request", InternalServerError}
+	errUpstreamMalformed   = apiError{http.StatusBadGateway, CodeUpstreamMalformed, "Malformed upstream response", InternalServerError}
+	errInternal            = apiError{http.StatusInternalServerError, CodeInternalError, "Internal server error", InternalServerError}
+	errInvalidParameter    = apiError{http.StatusBadRequest, CodeInvalidParameter, "Invalid parameter", InvalidParameter}
+	errNotImplemented      = apiError{http.StatusNotImplemented, CodeNotImplemented, "Not implemented", NotImplemented}
+	errInvalidBody         = apiError{http.StatusBadRequest, CodeInvalidBody, "Invalid request body", InvalidBody}
+	errLocationNotFound    = apiError{http.StatusNotFound, CodeLocationNotFound, "Location not found", CannotFindLocation}
+)
+
+// writeServiceError writes the response matching an error returned by the
+// services
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var circuitErr *internals.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(circuitErr.RetryAfter.Seconds())), 1)))
+	}
+	apiErr, detail := serviceError(err)
+	writeProblem(w, r, apiErr, detail)
+}
+
+// serviceError returns how an error returned by the services is reported,
+// along with the problem detail
+func serviceError(err error) (apiError, string) {
+	var circuitErr *internals.CircuitOpenError
+	switch {
+	case errors.Is(err, services.ErrCEPNotFound):
+		return errCEPNotFound, "no address was found for the CEP"
+	case errors.Is(err, services.ErrInvalidCEP):
+		return errInvalidCEP, "the CEP was rejected by the address provider"
+	case errors.As(err, &circuitErr):
+		return errUpstreamUnavailable, "an upstream service is failing, try again later"
+	case errors.Is(err, services.ErrLocationNotFound):
+		return errWeatherNotFound, "no weather was found for the address location"
+	case errors.Is(err, services.ErrRateLimited):
+		return errUpstreamRateLimited, "an upstream service is rate limiting requests, try again later"
+	case errors.Is(err, services.ErrUnauthorized):
+		return errUpstreamRejected, "an upstream service rejected the configured credentials"
+	case errors.Is(err, services.ErrMalformedResponse):
+		return errUpstreamMalformed, "an upstream service returned an unexpected response"
+	case errors.Is(err, services.ErrCEPServiceUnavailable):
+		return errUpstreamUnavailable, "no address provider is available, try again later"
+	case errors.Is(err, services.ErrUpstreamUnavailable):
+		return errUpstreamUnavailable, "an upstream service is unavailable, try again later"
+	default:
+		return errInternal, ""
+	}
+}
+
+// writeProblem writes apiErr as problem+json, or as the legacy plain text
+// body for clients asking for text/plain
+func writeProblem(w http.ResponseWriter, r *http.Request, apiErr apiError, detail string) {
+	if wantsPlainText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(apiErr.Status)
+		w.Write([]byte(apiErr.Text))
+		return
+	}
+
+	problem := newProblem(apiErr, detail)
+	problem.Instance = r.URL.Path
+	problem.RequestID = RequestID(r.Context())
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// newProblem returns the Problem describing apiErr
+func newProblem(apiErr apiError, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + strings.ReplaceAll(apiErr.Code, "_", "-"),
+		Title:  apiErr.Title,
+		Status: apiErr.Status,
+		Detail: detail,
+		Code:   apiErr.Code,
+	}
+}
+
+// wantsPlainText reports whether the client asked for text/plain rather
+// than JSON
+func wantsPlainText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "json")
+}
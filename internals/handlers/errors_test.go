@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteServiceErrorProblem(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{services.ErrCEPNotFound, http.StatusNotFound, CodeCEPNotFound},
+		{services.ErrInvalidCEP, http.StatusUnprocessableEntity, CodeInvalidCEP},
+		{fmt.Errorf("%w: timeout", services.ErrCEPServiceUnavailable), http.StatusServiceUnavailable, CodeUpstreamUnavailable},
+		{services.ErrLocationNotFound, http.StatusNotFound, CodeWeatherNotFound},
+		{&services.UpstreamError{Service: "weatherapi", Kind: services.ErrLocationNotFound, StatusCode: 400, Code: 1006}, http.StatusNotFound, CodeWeatherNotFound},
+		{&services.UpstreamError{Service: "weatherapi", Kind: services.ErrRateLimited, StatusCode: 429}, http.StatusServiceUnavailable, CodeUpstreamRateLimited},
+		{&services.UpstreamError{Service: "weatherapi", Kind: services.ErrUnauthorized, StatusCode: 401}, http.StatusBadGateway, CodeUpstreamRejected},
+		{&services.UpstreamError{Service: "viacep", Kind: services.ErrMalformedResponse}, http.StatusBadGateway, CodeUpstreamMalformed},
+		{&services.UpstreamError{Service: "openmeteo", Kind: services.ErrUpstreamUnavailable, Err: fmt.Errorf("timeout")}, http.StatusServiceUnavailable, CodeUpstreamUnavailable},
+		{fmt.Errorf("unexpected"), http.StatusInternalServerError, CodeInternalError},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			assert := assert.New(t)
+			req := httptest.NewRequest("GET", "/weather/01001000", nil)
+			req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "request-1"))
+			rr := httptest.NewRecorder()
+
+			writeServiceError(rr, req, test.err)
+
+			assert.Equal(test.status, rr.Code)
+			assert.Equal(ProblemContentType, rr.Header().Get("Content-Type"))
+			var problem Problem
+			assert.NoError(json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(test.status, problem.Status)
+			assert.Equal(test.code, problem.Code)
+			assert.Equal("request-1", problem.RequestID)
+			assert.Equal("/weather/01001000", problem.Instance)
+			assert.NotEmpty(problem.Type)
+			assert.NotEmpty(problem.Title)
+		})
+	}
+}
+
+func TestWriteServiceErrorPlainText(t *testing.T) {
+	assert := assert.New(t)
+	req := httptest.NewRequest("GET", "/weather/01001000", nil)
+	req.Header.Set("Accept", "text/plain")
+	rr := httptest.NewRecorder()
+
+	writeServiceError(rr, req, services.ErrCEPNotFound)
+
+	assert.Equal(http.StatusNotFound, rr.Code)
+	assert.Equal("text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(CannotFindZipCode, rr.Body.String())
+}
+
+func TestWantsPlainText(t *testing.T) {
+	assert := assert.New(t)
+	for accept, expected := range map[string]bool{
+		"":                                     false,
+		"*/*":                                  false,
+		"text/plain":                           true,
+		"text/plain, application/problem+json": false,
+		"application/json":                     false,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		assert.Equal(expected, wantsPlainText(req), accept)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/rcbadiale/go-cloud-run/internals/units"
+)
+
+type GetForecastResponse struct {
+	Days []ForecastDayResponse `json:"days"`
+}
+
+type ForecastDayResponse struct {
+	Date         string                 `json:"date"`
+	Min          Temperature            `json:"min"`
+	Max          Temperature            `json:"max"`
+	ChanceOfRain int                    `json:"chance_of_rain"`
+	PrecipMm     *float64               `json:"precip_mm,omitempty"`
+	PrecipIn     *float64               `json:"precip_in,omitempty"`
+	Condition    string                 `json:"condition"`
+	Hours        []ForecastHourResponse `json:"hours"`
+}
+
+type ForecastHourResponse struct {
+	Time time.Time `json:"time"`
+	Temperature
+	ChanceOfRain int    `json:"chance_of_rain"`
+	Condition    string `json:"condition"`
+}
+
+// GetForecast returns the daily and hourly forecast for the next days
+func (wh *WeatherHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	if wh.ForecastService == nil {
+		writeProblem(w, r, errNotImplemented, "the configured weather provider does not support forecasts")
+		return
+	}
+	days, err := parseDays(r.URL.Query().Get("days"))
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	format, err := parseOutputFormat(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	address, ok := resolveAddress(w, r, wh.CEPService)
+	if !ok {
+		return
+	}
+	forecast, err := wh.forecastForAddress(r.Context(), address, days)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newForecastResponse(forecast, format))
+}
+
+// forecastForAddress looks the forecast up by the address coordinates, using
+// a city, state and country query when they can not be resolved
+func (wh *WeatherHandler) forecastForAddress(ctx context.Context, address *services.Address, days int) (*services.Forecast, error) {
+	return lookupAt(ctx, address, wh.coordinatesFor(ctx, address),
+		func(ctx context.Context, lat, lon float64) (*services.Forecast, error) {
+			return wh.ForecastService.GetForecastByCoordinates(ctx, lat, lon, days)
+		},
+		func(ctx context.Context, query string) (*services.Forecast, error) {
+			return wh.ForecastService.GetForecastByCity(ctx, query, days)
+		},
+	)
+}
+
+// parseDays parses the days query parameter, defaulting to
+// services.Forecast_DefaultDays
+func parseDays(value string) (int, error) {
+	if value == "" {
+		return services.Forecast_DefaultDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > services.Forecast_MaxDays {
+		return 0, fmt.Errorf("days must be a number from 1 to %d", services.Forecast_MaxDays)
+	}
+	return days, nil
+}
+
+// newForecastResponse converts a Forecast to the requested units
+func newForecastResponse(forecast *services.Forecast, format outputFormat) GetForecastResponse {
+	return GetForecastResponse{Days: newDaysResponse(forecast.Days, format)}
+}
+
+// newDaysResponse converts forecast days to the requested units
+func newDaysResponse(days []services.ForecastDay, format outputFormat) []ForecastDayResponse {
+	response := make([]ForecastDayResponse, 0, len(days))
+	for _, day := range days {
+		dayResponse := ForecastDayResponse{
+			Date:         day.Date.Format(time.DateOnly),
+			Min:          format.temperature(day.MinTempC, day.MinTempF),
+			Max:          format.temperature(day.MaxTempC, day.MaxTempF),
+			ChanceOfRain: day.ChanceOfRain,
+			PrecipMm:     format.value(day.PrecipMm, units.Metric, units.SI),
+			PrecipIn:     format.value(units.MillimetreToInch(day.PrecipMm), units.Imperial),
+			Condition:    day.Condition,
+			Hours:        make([]ForecastHourResponse, 0, len(day.Hours)),
+		}
+		for _, hour := range day.Hours {
+			dayResponse.Hours = append(dayResponse.Hours, ForecastHourResponse{
+				Time:         hour.Time,
+				Temperature:  format.temperature(hour.TempC, hour.TempF),
+				ChanceOfRain: hour.ChanceOfRain,
+				Condition:    hour.Condition,
+			})
+		}
+		response = append(response, dayResponse)
+	}
+	return response
+}
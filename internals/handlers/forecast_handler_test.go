@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockForecastService struct {
+	mock.Mock
+}
+
+func (m *MockForecastService) GetForecastByCity(ctx context.Context, city string, days int) (*services.Forecast, error) {
+	args := m.Called(ctx, city, days)
+	return args.Get(0).(*services.Forecast), args.Error(1)
+}
+
+func (m *MockForecastService) GetForecastByCoordinates(ctx context.Context, lat, lon float64, days int) (*services.Forecast, error) {
+	args := m.Called(ctx, lat, lon, days)
+	return args.Get(0).(*services.Forecast), args.Error(1)
+}
+
+func TestGetForecast(t *testing.T) {
+	location := time.FixedZone("-03", -3*60*60)
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{
+		City: "Florianópolis", State: "SC", Coordinates: &services.Coordinates{Lat: -27.59, Lon: -48.54},
+	}, nil)
+	mockForecastService := new(MockForecastService)
+	mockForecastService.On("GetForecastByCoordinates", mock.Anything, -27.59, -48.54, 2).Return(&services.Forecast{
+		Days: []services.ForecastDay{{
+			Date:         time.Date(2024, 5, 24, 0, 0, 0, 0, location),
+			MinTempC:     17.36,
+			MinTempF:     63.25,
+			MaxTempC:     24.18,
+			MaxTempF:     75.52,
+			ChanceOfRain: 86,
+			PrecipMm:     3.5,
+			Condition:    "Patchy rain nearby",
+			Hours: []services.ForecastHour{
+				{Time: time.Date(2024, 5, 24, 12, 0, 0, 0, location), TempC: 23.95, TempF: 75.11, ChanceOfRain: 86, Condition: "Patchy rain nearby"},
+			},
+		}},
+	}, nil)
+
+	handler := &WeatherHandler{CEPService: mockCEPService, ForecastService: mockForecastService}
+	rr := serve(t, "GET", "/weather/{zipCode}/forecast", handler.GetForecast, "/weather/88010000/forecast?days=2", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	expected := `{"days":[{"date":"2024-05-24","min":{"temp_c":17.4,"temp_f":63.3,"temp_k":290.5},"max":{"temp_c":24.2,"temp_f":75.5,"temp_k":297.3},"chance_of_rain":86,"precip_mm":3.5,"precip_in":0.1,"condition":"Patchy rain nearby","hours":[{"time":"2024-05-24T12:00:00-03:00","temp_c":24,"temp_f":75.1,"temp_k":297.1,"chance_of_rain":86,"condition":"Patchy rain nearby"}]}]}`
+	assert.Equal(t, expected, strings.TrimRight(rr.Body.String(), "\n"))
+	mockForecastService.AssertExpectations(t)
+}
+
+func TestGetForecastDefaultDaysByCity(t *testing.T) {
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{City: "Florianópolis", State: "SC"}, nil)
+	mockForecastService := new(MockForecastService)
+	mockForecastService.On("GetForecastByCity", mock.Anything, "Florianópolis, Santa Catarina, Brazil", services.Forecast_DefaultDays).Return(&services.Forecast{}, nil)
+
+	handler := &WeatherHandler{CEPService: mockCEPService, ForecastService: mockForecastService}
+	rr := serve(t, "GET", "/weather/{zipCode}/forecast", handler.GetForecast, "/weather/88010000/forecast", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"days":[]}`, strings.TrimRight(rr.Body.String(), "\n"))
+	mockForecastService.AssertExpectations(t)
+}
+
+func TestGetForecastInvalidDays(t *testing.T) {
+	handler := &WeatherHandler{CEPService: new(MockViaCEPService), ForecastService: new(MockForecastService)}
+	for _, days := range []string{"0", "15", "two", "-1"} {
+		rr := serve(t, "GET", "/weather/{zipCode}/forecast", handler.GetForecast, "/weather/88010000/forecast?days="+days, "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, days)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_parameter"`)
+	}
+}
+
+func TestGetForecastNotSupported(t *testing.T) {
+	handler := &WeatherHandler{CEPService: new(MockViaCEPService)}
+	rr := serve(t, "GET", "/weather/{zipCode}/forecast", handler.GetForecast, "/weather/88010000/forecast", "")
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"not_implemented"`)
+}
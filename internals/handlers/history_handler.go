@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+// now returns the current time, replaced in tests
+var now = time.Now
+
+type GetHistoryResponse struct {
+	Days []ForecastDayResponse `json:"days"`
+}
+
+// GetHistory returns the daily and hourly weather observed on a date, or
+// from and to given dates
+func (wh *WeatherHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	if wh.HistoryService == nil {
+		writeProblem(w, r, errNotImplemented, "the configured weather provider does not support history")
+		return
+	}
+	from, to, err := parseDateRange(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	format, err := parseOutputFormat(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	address, ok := resolveAddress(w, r, wh.CEPService)
+	if !ok {
+		return
+	}
+	history, err := wh.historyForAddress(r.Context(), address, from, to)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(GetHistoryResponse{Days: newDaysResponse(history.Days, format)})
+}
+
+// historyForAddress looks the history up by the address coordinates, using a
+// city, state and country query when they can not be resolved
+func (wh *WeatherHandler) historyForAddress(ctx context.Context, address *services.Address, from, to time.Time) (*services.History, error) {
+	return lookupAt(ctx, address, wh.coordinatesFor(ctx, address),
+		func(ctx context.Context, lat, lon float64) (*services.History, error) {
+			return wh.HistoryService.GetHistoryByCoordinates(ctx, lat, lon, from, to)
+		},
+		func(ctx context.Context, query string) (*services.History, error) {
+			return wh.HistoryService.GetHistoryByCity(ctx, query, from, to)
+		},
+	)
+}
+
+// parseDateRange parses either the date or the from and to query parameters,
+// within services.History_MinDate and today
+func parseDateRange(query url.Values) (time.Time, time.Time, error) {
+	date, from, to := query.Get("date"), query.Get("from"), query.Get("to")
+	switch {
+	case date != "" && (from != "" || to != ""):
+		return time.Time{}, time.Time{}, errors.New("date can not be used with from and to")
+	case date != "":
+		from, to = date, date
+	case from == "" || to == "":
+		return time.Time{}, time.Time{}, errors.New("either date or both from and to are required")
+	}
+
+	fromDate, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", from)
+	}
+	toDate, err := time.Parse(time.DateOnly, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", to)
+	}
+	// Dates are compared in UTC, which is ahead of every Brazilian time zone
+	today := now().UTC().Truncate(24 * time.Hour)
+	switch {
+	case fromDate.Before(services.History_MinDate):
+		return time.Time{}, time.Time{}, fmt.Errorf("dates must not be before %s", services.History_MinDate.Format(time.DateOnly))
+	case toDate.After(today):
+		return time.Time{}, time.Time{}, errors.New("dates must not be in the future")
+	case toDate.Before(fromDate):
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	case toDate.Sub(fromDate) >= services.History_MaxDays*24*time.Hour:
+		return time.Time{}, time.Time{}, fmt.Errorf("the range must not be longer than %d days", services.History_MaxDays)
+	}
+	return fromDate, toDate, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHistoryService struct {
+	mock.Mock
+}
+
+func (m *MockHistoryService) GetHistoryByCity(ctx context.Context, city string, from, to time.Time) (*services.History, error) {
+	args := m.Called(ctx, city, from, to)
+	return args.Get(0).(*services.History), args.Error(1)
+}
+
+func (m *MockHistoryService) GetHistoryByCoordinates(ctx context.Context, lat, lon float64, from, to time.Time) (*services.History, error) {
+	args := m.Called(ctx, lat, lon, from, to)
+	return args.Get(0).(*services.History), args.Error(1)
+}
+
+// setNow makes the handlers run as if at was the current time
+func setNow(t *testing.T, at time.Time) {
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestGetHistory(t *testing.T) {
+	setNow(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	day := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{
+		City: "Florianópolis", State: "SC", Coordinates: &services.Coordinates{Lat: -27.59, Lon: -48.54},
+	}, nil)
+	mockHistoryService := new(MockHistoryService)
+	mockHistoryService.On("GetHistoryByCoordinates", mock.Anything, -27.59, -48.54, day, day).Return(&services.History{
+		Days: []services.ForecastDay{{Date: day, MinTempC: 15.5, MinTempF: 59.9, MaxTempC: 22, MaxTempF: 71.6, Condition: "Sunny"}},
+	}, nil)
+
+	handler := &WeatherHandler{CEPService: mockCEPService, HistoryService: mockHistoryService}
+	rr := serve(t, "GET", "/weather/{zipCode}/history", handler.GetHistory, "/weather/88010000/history?date=2024-05-20", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	expected := `{"days":[{"date":"2024-05-20","min":{"temp_c":15.5,"temp_f":59.9,"temp_k":288.7},"max":{"temp_c":22,"temp_f":71.6,"temp_k":295.2},"chance_of_rain":0,"precip_mm":0,"precip_in":0,"condition":"Sunny","hours":[]}]}`
+	assert.Equal(t, expected, strings.TrimRight(rr.Body.String(), "\n"))
+	mockHistoryService.AssertExpectations(t)
+}
+
+func TestGetHistoryRangeByCity(t *testing.T) {
+	setNow(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{City: "Florianópolis", State: "SC"}, nil)
+	mockHistoryService := new(MockHistoryService)
+	mockHistoryService.On("GetHistoryByCity", mock.Anything, "Florianópolis, Santa Catarina, Brazil", from, to).Return(&services.History{}, nil)
+
+	handler := &WeatherHandler{CEPService: mockCEPService, HistoryService: mockHistoryService}
+	rr := serve(t, "GET", "/weather/{zipCode}/history", handler.GetHistory, "/weather/88010000/history?from=2024-05-01&to=2024-05-30", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockHistoryService.AssertExpectations(t)
+}
+
+func TestGetHistoryErrorMapping(t *testing.T) {
+	setNow(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{City: "Florianópolis", State: "SC"}, nil)
+	mockHistoryService := new(MockHistoryService)
+	mockHistoryService.On("GetHistoryByCity", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		(*services.History)(nil), &services.UpstreamError{Service: "weatherapi", Kind: services.ErrLocationNotFound, Code: 1006},
+	)
+
+	handler := &WeatherHandler{CEPService: mockCEPService, HistoryService: mockHistoryService}
+	rr := serve(t, "GET", "/weather/{zipCode}/history", handler.GetHistory, "/weather/88010000/history?date=2024-05-20", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"weather_not_found"`)
+}
+
+func TestGetHistoryInvalidDates(t *testing.T) {
+	setNow(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	handler := &WeatherHandler{CEPService: new(MockViaCEPService), HistoryService: new(MockHistoryService)}
+	for _, query := range []string{
+		"",
+		"date=20-05-2024",
+		"date=2024-02-30",
+		"date=2024-06-02",
+		"date=2009-12-31",
+		"date=2024-05-20&from=2024-05-01",
+		"from=2024-05-01",
+		"from=2024-05-20&to=2024-05-01",
+		"from=2024-04-01&to=2024-05-01",
+	} {
+		rr := serve(t, "GET", "/weather/{zipCode}/history", handler.GetHistory, "/weather/88010000/history?"+query, "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_parameter"`, query)
+	}
+}
+
+func TestParseDateRangeToday(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 6, 1, 23, 59, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	from, to, err := parseDateRange(url.Values{"date": {"2024-06-01"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, from, to)
+}
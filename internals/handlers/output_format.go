@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/rcbadiale/go-cloud-run/internals/units"
+)
+
+const (
+	DefaultPrecision = 1
+	MaxPrecision     = 6
+	DefaultRounding  = units.HalfUp
+)
+
+// Temperature is a temperature in the Celsius, Fahrenheit and Kelvin scales
+// of the requested unit system
+type Temperature struct {
+	TempC *float64 `json:"temp_c,omitempty"`
+	TempF *float64 `json:"temp_f,omitempty"`
+	TempK *float64 `json:"temp_k,omitempty"`
+}
+
+// outputFormat is how measurements are reported, set by the units, precision
+// and rounding query parameters
+type outputFormat struct {
+	System    units.System
+	Precision int
+	Rounding  units.RoundingMode
+}
+
+// defaultOutputFormat reports every unit with one rounded decimal place
+var defaultOutputFormat = outputFormat{System: units.All, Precision: DefaultPrecision, Rounding: DefaultRounding}
+
+// parseOutputFormat parses the units, precision and rounding query
+// parameters, using defaultOutputFormat for the missing ones
+func parseOutputFormat(query url.Values) (outputFormat, error) {
+	format := defaultOutputFormat
+	var err error
+	if value := query.Get("units"); value != "" {
+		if format.System, err = units.ParseSystem(value); err != nil {
+			return format, err
+		}
+	}
+	if value := query.Get("precision"); value != "" {
+		format.Precision, err = strconv.Atoi(value)
+		if err != nil || format.Precision < 0 || format.Precision > MaxPrecision {
+			return format, fmt.Errorf("precision must be a number from 0 to %d", MaxPrecision)
+		}
+	}
+	if value := query.Get("rounding"); value != "" {
+		if format.Rounding, err = units.ParseRoundingMode(value); err != nil {
+			return format, err
+		}
+	}
+	return format, nil
+}
+
+// temperature converts a temperature to the requested scales, Fahrenheit
+// being reported by the provider
+func (f outputFormat) temperature(celsius, fahrenheit float64) Temperature {
+	return Temperature{
+		TempC: f.value(celsius, units.Metric),
+		TempF: f.value(fahrenheit, units.Imperial),
+		TempK: f.value(units.CelsiusToKelvin(celsius), units.SI),
+	}
+}
+
+// value rounds value when any of systems is requested, returning nil
+// otherwise
+func (f outputFormat) value(value float64, systems ...units.System) *float64 {
+	for _, system := range systems {
+		if f.System.Includes(system) {
+			rounded := units.Round(value, f.Precision, f.Rounding)
+			return &rounded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/units"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	format, err := parseOutputFormat(url.Values{})
+	assert.Nil(err)
+	assert.Equal(defaultOutputFormat, format)
+
+	format, err = parseOutputFormat(url.Values{"units": {"Imperial"}, "precision": {"2"}, "rounding": {"truncate"}})
+	assert.Nil(err)
+	assert.Equal(outputFormat{System: units.Imperial, Precision: 2, Rounding: units.Truncate}, format)
+
+	for _, query := range []url.Values{
+		{"units": {"kelvin"}},
+		{"precision": {"-1"}},
+		{"precision": {"7"}},
+		{"precision": {"one"}},
+		{"rounding": {"ceil"}},
+	} {
+		_, err = parseOutputFormat(query)
+		assert.Error(err, query.Encode())
+	}
+}
+
+func TestOutputFormatTemperature(t *testing.T) {
+	assert := assert.New(t)
+	metric := outputFormat{System: units.Metric, Precision: 1, Rounding: units.HalfUp}
+
+	temperature := metric.temperature(-0.25, 31.55)
+
+	assert.Equal(-0.3, *temperature.TempC)
+	assert.Nil(temperature.TempF)
+	assert.Nil(temperature.TempK)
+
+	all := outputFormat{System: units.All, Precision: 0, Rounding: units.HalfEven}
+	temperature = all.temperature(-0.5, 31.1)
+	assert.Equal(float64(0), *temperature.TempC)
+	assert.Equal(float64(31), *temperature.TempF)
+	assert.Equal(float64(273), *temperature.TempK)
+}
+
+func TestGetWeatherUnits(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"units=metric", `{"temp_c":21,"wind":{"speed_kph":13,"gust_kph":20.2,"degree":110,"direction":"ESE"},"pressure_mb":1008,"precip_mm":0}`},
+		{"units=imperial&precision=2", `{"temp_f":69.8,"wind":{"speed_mph":8.08,"gust_mph":12.55,"degree":110,"direction":"ESE"},"pressure_in":29.77,"precip_in":0}`},
+		{"units=si&precision=3&rounding=truncate", `{"temp_k":294.15,"wind":{"speed_mps":3.611,"gust_mps":5.611,"degree":110,"direction":"ESE"},"pressure_pa":100800,"precip_mm":0.04}`},
+	}
+	for _, test := range tests {
+		rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000?fields=wind,pressure,precipitation&"+test.query)
+
+		assert.Equal(t, http.StatusOK, rr.Code, test.query)
+		assert.Equal(t, test.expected, strings.TrimRight(rr.Body.String(), "\n"), test.query)
+	}
+}
+
+func TestGetWeatherInvalidUnits(t *testing.T) {
+	rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000?units=nautical")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"invalid_parameter"`)
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import "context"
+
+type contextKey string
+
+// RequestIDKey is the context key holding the id generated for each request
+const RequestIDKey = contextKey("request_id")
+
+// RequestID returns the id of the request handled with ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
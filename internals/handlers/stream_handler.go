@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+const (
+	Stream_Workers      = 8
+	Stream_MaxLineBytes = 4 << 10
+	NDJSONContentType   = "application/x-ndjson"
+)
+
+// StreamWeatherResult is a BatchWeatherResult along with the index of its
+// line in the request, as results are streamed in completion order
+type StreamWeatherResult struct {
+	Index int `json:"index"`
+	BatchWeatherResult
+}
+
+// StreamError is the last line of a stream whose request could not be read
+// to the end
+type StreamError struct {
+	Error Problem `json:"error"`
+}
+
+// streamJob is a CEP read from the request, or the error of its line
+type streamJob struct {
+	index int
+	cep   string
+	err   error
+}
+
+// StreamWeather reads newline-delimited CEPs and writes newline-delimited
+// results as each lookup completes, with at most StreamWorkers lookups in
+// flight. Lines are read only as workers free up, so slow lookups or a slow
+// client hold the request back instead of buffering it
+func (wh *WeatherHandler) StreamWeather(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	format, err := parseOutputFormat(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	controller := http.NewResponseController(w)
+	// HTTP/1 stops reading the request once the response starts, HTTP/2 is
+	// always full duplex so an error here can be ignored
+	controller.EnableFullDuplex()
+	// A stream lasts as long as the client keeps sending CEPs, so the server
+	// read and write timeouts do not apply
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	jobs := make(chan streamJob)
+	results := make(chan StreamWeatherResult)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		readErr <- readStreamCEPs(ctx, r.Body, jobs)
+	}()
+
+	workers := wh.StreamWorkers
+	if workers <= 0 {
+		workers = Stream_Workers
+	}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result := StreamWeatherResult{Index: job.index, BatchWeatherResult: BatchWeatherResult{Cep: job.cep}}
+				if job.err != nil {
+					problem := newProblem(errInvalidBody, job.err.Error())
+					result.Error = &problem
+				} else {
+					result.BatchWeatherResult = wh.lookupWeather(ctx, job.cep, fields, format)
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+	encoder := json.NewEncoder(w)
+	for result := range results {
+		if err := encoder.Encode(result); err != nil {
+			// The client went away, the deferred cancel stops the workers
+			return
+		}
+		controller.Flush()
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	// Without cancellation the workers only stop once every line was read
+	if err := <-readErr; err != nil {
+		encoder.Encode(StreamError{Error: newProblem(errInvalidBody, err.Error())})
+		controller.Flush()
+	}
+}
+
+// readStreamCEPs sends a job for each non-blank line of body, until it ends
+// or ctx is done
+func readStreamCEPs(ctx context.Context, body io.Reader, jobs chan<- streamJob) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, Stream_MaxLineBytes), Stream_MaxLineBytes)
+	index := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		cep, err := parseStreamLine(line)
+		select {
+		case jobs <- streamJob{index: index, cep: cep, err: err}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		index++
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("line %d is longer than %d bytes", index, Stream_MaxLineBytes)
+	}
+	return scanner.Err()
+}
+
+// parseStreamLine returns the CEP of a line, either a bare CEP, a JSON string
+// or a JSON object with a cep field
+func parseStreamLine(line string) (string, error) {
+	switch line[0] {
+	case '{':
+		var item struct {
+			Cep string `json:"cep"`
+		}
+		if err := json.Unmarshal([]byte(line), &item); err != nil || item.Cep == "" {
+			return "", errors.New("the line must be a CEP, a JSON string or an object with a cep field")
+		}
+		return item.Cep, nil
+	case '"':
+		var cep string
+		if err := json.Unmarshal([]byte(line), &cep); err != nil {
+			return "", errors.New("the line must be a CEP, a JSON string or an object with a cep field")
+		}
+		return cep, nil
+	default:
+		return line, nil
+	}
+}
+
+// lookupWeather returns the weather of a single CEP, or the problem that
+// prevented looking it up
+func (wh *WeatherHandler) lookupWeather(ctx context.Context, value string, fields map[string]bool, format outputFormat) BatchWeatherResult {
+	result := BatchWeatherResult{Cep: value}
+	var address *services.Address
+	var weather *services.Weather
+	zipCode, err := cep.Parse(value)
+	if err == nil {
+		address, err = wh.CEPService.GetAddressByCEP(ctx, zipCode)
+		if err == nil {
+			weather, err = wh.weatherForAddress(ctx, address)
+		}
+	}
+	if err != nil {
+		problem := batchProblem(err)
+		result.Error = &problem
+		return result
+	}
+	output := newWeatherResponse(address, weather, fields, format)
+	result.Weather = &output
+	return result
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// bodyLines returns the lines of a streamed response body
+func bodyLines(rr *httptest.ResponseRecorder) []string {
+	return strings.Split(strings.TrimRight(rr.Body.String(), "\n"), "\n")
+}
+
+func TestStreamWeather(t *testing.T) {
+	cepService := &concurrentCEPService{
+		cities: map[string]string{"01001000": "São Paulo", "13405162": "Piracicaba"},
+		calls:  map[string]int{},
+	}
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, mock.Anything).Return(&services.Weather{Current: services.CurrentWeather{TempC: 20, TempF: 68}}, nil)
+	handler := &WeatherHandler{CEPService: cepService, WeatherService: mockWeatherService, StreamWorkers: 2}
+	body := "01001000\n\n{\"cep\": \"13405162\"}\n\"99999999\"\n123\n{\"zip\": 1}\n"
+
+	rr := serve(t, "POST", "/weather/stream", handler.StreamWeather, "/weather/stream?units=metric", body)
+	lines := bodyLines(rr)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Equal(NDJSONContentType, rr.Header().Get("Content-Type"))
+	assert.Len(lines, 5)
+	results := make([]StreamWeatherResult, len(lines))
+	for i, line := range lines {
+		assert.NoError(json.Unmarshal([]byte(line), &results[i]), line)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	assert.Equal("01001000", results[0].Cep)
+	assert.Equal(float64(20), *results[0].Weather.TempC)
+	assert.Equal("13405162", results[1].Cep)
+	assert.NotNil(results[1].Weather)
+	assert.Equal(CodeCEPNotFound, results[2].Error.Code)
+	assert.Equal(CodeInvalidCEP, results[3].Error.Code)
+	assert.Equal(CodeInvalidBody, results[4].Error.Code)
+	assert.Equal(4, results[4].Index)
+}
+
+func TestStreamWeatherBoundsWorkers(t *testing.T) {
+	cepService := &concurrentCEPService{cities: map[string]string{}, calls: map[string]int{}}
+	handler := &WeatherHandler{CEPService: cepService, StreamWorkers: 3}
+	ceps := make([]string, 20)
+	for i := range ceps {
+		ceps[i] = fmt.Sprintf("%08d", 1000000+i)
+	}
+
+	rr := serve(t, "POST", "/weather/stream", handler.StreamWeather, "/weather/stream", strings.Join(ceps, "\n"))
+	lines := bodyLines(rr)
+
+	assert.Len(t, lines, 20)
+	assert.Len(t, cepService.calls, 20)
+	assert.LessOrEqual(t, cepService.peak.Load(), int32(3))
+	assert.Greater(t, cepService.peak.Load(), int32(1))
+}
+
+func TestStreamWeatherLineTooLong(t *testing.T) {
+	handler := &WeatherHandler{CEPService: &concurrentCEPService{calls: map[string]int{}}}
+
+	rr := serve(t, "POST", "/weather/stream", handler.StreamWeather, "/weather/stream", "12345678\n"+strings.Repeat("1", Stream_MaxLineBytes+1)+"\n")
+	lines := bodyLines(rr)
+
+	assert := assert.New(t)
+	assert.Len(lines, 2)
+	assert.Contains(lines[0], `"code":"cep_not_found"`)
+	var streamErr StreamError
+	assert.NoError(json.Unmarshal([]byte(lines[1]), &streamErr))
+	assert.Equal(CodeInvalidBody, streamErr.Error.Code)
+}
+
+// gatedCEPService resolves a CEP only once its gate is released
+type gatedCEPService struct {
+	gates map[string]chan struct{}
+}
+
+func (g *gatedCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*services.Address, error) {
+	select {
+	case <-g.gates[cep.Digits()]:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return nil, services.ErrCEPNotFound
+}
+
+func TestStreamWeatherStreamsBeforeRequestEnds(t *testing.T) {
+	cepService := &gatedCEPService{gates: map[string]chan struct{}{
+		"11111111": make(chan struct{}),
+		"22222222": make(chan struct{}),
+	}}
+	r := chi.NewRouter()
+	r.Post("/weather/stream", (&WeatherHandler{CEPService: cepService}).StreamWeather)
+	server := httptest.NewUnstartedServer(r)
+	// The stream outlives the server timeouts
+	server.Config.ReadTimeout = 50 * time.Millisecond
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	body, requestWriter := io.Pipe()
+	defer requestWriter.Close()
+	req, _ := http.NewRequest("POST", server.URL+"/weather/stream", body)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+
+	// The second CEP completes first, while the request is still open
+	io.WriteString(requestWriter, "11111111\n22222222\n")
+	time.Sleep(100 * time.Millisecond)
+	close(cepService.gates["22222222"])
+	assert.True(t, lines.Scan())
+	assert.Contains(t, lines.Text(), `"cep":"22222222"`)
+
+	close(cepService.gates["11111111"])
+	assert.True(t, lines.Scan())
+	assert.Contains(t, lines.Text(), `"cep":"11111111"`)
+
+	requestWriter.Close()
+	assert.False(t, lines.Scan())
+}
+
+func TestParseStreamLine(t *testing.T) {
+	assert := assert.New(t)
+	for line, expected := range map[string]string{
+		`01001000`:            "01001000",
+		`"01001000"`:          "01001000",
+		`{"cep": "01001000"}`: "01001000",
+		`{"cep":"01001-000"}`: "01001-000",
+	} {
+		cep, err := parseStreamLine(line)
+		assert.Nil(err, line)
+		assert.Equal(expected, cep, line)
+	}
+	for _, line := range []string{`{"cep": 1}`, `{}`, `"01001000`} {
+		_, err := parseStreamLine(line)
+		assert.Error(err, line)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/rcbadiale/go-cloud-run/internals/units"
+)
+
+// Optional fields of GetWeatherResponse, selected with the fields query
+// parameter or all at once with detail=full
+const (
+	FieldFeelsLike     = "feels_like"
+	FieldCondition     = "condition"
+	FieldHumidity      = "humidity"
+	FieldWind          = "wind"
+	FieldPressure      = "pressure"
+	FieldPrecipitation = "precipitation"
+	FieldVisibility    = "visibility"
+	FieldUV            = "uv"
+	FieldCloud         = "cloud"
+	FieldObservedAt    = "observed_at"
+	FieldAddress       = "address"
+
+	DetailBasic = "basic"
+	DetailFull  = "full"
+)
+
+var WeatherFields = []string{
+	FieldFeelsLike, FieldCondition, FieldHumidity, FieldWind, FieldPressure, FieldPrecipitation,
+	FieldVisibility, FieldUV, FieldCloud, FieldObservedAt, FieldAddress,
+}
+
+type WindResponse struct {
+	SpeedKph  *float64 `json:"speed_kph,omitempty"`
+	SpeedMph  *float64 `json:"speed_mph,omitempty"`
+	SpeedMps  *float64 `json:"speed_mps,omitempty"`
+	GustKph   *float64 `json:"gust_kph,omitempty"`
+	GustMph   *float64 `json:"gust_mph,omitempty"`
+	GustMps   *float64 `json:"gust_mps,omitempty"`
+	Degree    int      `json:"degree"`
+	Direction string   `json:"direction"`
+}
+
+type AddressResponse struct {
+	Cep          string `json:"cep"`
+	Street       string `json:"street"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+}
+
+// newAddressResponse returns the public fields of address
+func newAddressResponse(address *services.Address) AddressResponse {
+	return AddressResponse{
+		Cep:          address.Cep,
+		Street:       address.Street,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		State:        address.State,
+	}
+}
+
+// parseFields returns the optional fields selected by the fields and detail
+// query parameters
+func parseFields(query url.Values) (map[string]bool, error) {
+	fields := map[string]bool{}
+	switch detail := query.Get("detail"); detail {
+	case "", DetailBasic:
+	case DetailFull:
+		for _, field := range WeatherFields {
+			fields[field] = true
+		}
+	default:
+		return nil, fmt.Errorf("unknown detail %q, expected %s or %s", detail, DetailBasic, DetailFull)
+	}
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !isWeatherField(field) {
+			return nil, fmt.Errorf("unknown field %q, expected any of %s", field, strings.Join(WeatherFields, ", "))
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// isWeatherField reports whether field is one of WeatherFields
+func isWeatherField(field string) bool {
+	for _, known := range WeatherFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// newWeatherResponse converts the weather of address into a
+// GetWeatherResponse with the selected optional fields
+func newWeatherResponse(address *services.Address, weather *services.Weather, fields map[string]bool, format outputFormat) GetWeatherResponse {
+	current := weather.Current
+	response := GetWeatherResponse{Temperature: format.temperature(current.TempC, current.TempF)}
+	if fields[FieldFeelsLike] {
+		feelsLike := format.temperature(current.FeelsLikeC, current.FeelsLikeF)
+		response.FeelsLike = &feelsLike
+	}
+	if fields[FieldCondition] {
+		response.Condition = &current.Condition
+		response.IsDay = &current.IsDay
+	}
+	if fields[FieldHumidity] {
+		response.Humidity = &current.Humidity
+	}
+	if fields[FieldWind] {
+		response.Wind = &WindResponse{
+			SpeedKph:  format.value(current.WindKph, units.Metric),
+			SpeedMph:  format.value(units.KphToMph(current.WindKph), units.Imperial),
+			SpeedMps:  format.value(units.KphToMps(current.WindKph), units.SI),
+			GustKph:   format.value(current.GustKph, units.Metric),
+			GustMph:   format.value(units.KphToMph(current.GustKph), units.Imperial),
+			GustMps:   format.value(units.KphToMps(current.GustKph), units.SI),
+			Degree:    current.WindDegree,
+			Direction: current.WindDir,
+		}
+	}
+	if fields[FieldPressure] {
+		response.PressureMb = format.value(current.PressureMb, units.Metric)
+		response.PressureIn = format.value(units.MillibarToInHg(current.PressureMb), units.Imperial)
+		response.PressurePa = format.value(units.MillibarToPascal(current.PressureMb), units.SI)
+	}
+	if fields[FieldPrecipitation] {
+		response.PrecipMm = format.value(current.PrecipMm, units.Metric, units.SI)
+		response.PrecipIn = format.value(units.MillimetreToInch(current.PrecipMm), units.Imperial)
+	}
+	if fields[FieldVisibility] {
+		response.VisKm = format.value(current.VisKm, units.Metric)
+		response.VisMiles = format.value(units.KilometreToMile(current.VisKm), units.Imperial)
+		response.VisM = format.value(units.KilometreToMetre(current.VisKm), units.SI)
+	}
+	if fields[FieldUV] {
+		response.Uv = &current.Uv
+	}
+	if fields[FieldCloud] {
+		response.Cloud = &current.Cloud
+	}
+	if fields[FieldObservedAt] && !current.ObservedAt.IsZero() {
+		observedAt := current.ObservedAt.UTC().Truncate(time.Second)
+		response.ObservedAt = &observedAt
+	}
+	if fields[FieldAddress] && address != nil {
+		output := newAddressResponse(address)
+		response.Address = &output
+	}
+	return response
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newFieldsTestHandler returns a handler resolving 88010000 to a fixed
+// address and weather
+func newFieldsTestHandler() *WeatherHandler {
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{
+		Cep:          "88010-000",
+		Street:       "Rua Felipe Schmidt",
+		Neighborhood: "Centro",
+		City:         "Florianópolis",
+		State:        "SC",
+		Coordinates:  &services.Coordinates{Lat: -27.59, Lon: -48.54},
+	}, nil)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, -27.59, -48.54).Return(&services.Weather{
+		Current: services.CurrentWeather{
+			ObservedAt: time.Date(2024, 5, 24, 19, 15, 0, 0, time.UTC),
+			TempC:      21,
+			TempF:      69.8,
+			FeelsLikeC: 0,
+			FeelsLikeF: 32,
+			IsDay:      false,
+			Condition:  "Overcast",
+			WindKph:    13,
+			WindDegree: 110,
+			WindDir:    "ESE",
+			GustKph:    20.2,
+			PressureMb: 1008,
+			PrecipMm:   0.04,
+			Humidity:   83,
+			Cloud:      100,
+			VisKm:      10,
+			Uv:         0,
+		},
+	}, nil)
+	return &WeatherHandler{CEPService: mockCEPService, WeatherService: mockWeatherService}
+}
+
+func TestGetWeatherDefaultFields(t *testing.T) {
+	rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"temp_c":21,"temp_f":69.8,"temp_k":294.2}`, strings.TrimRight(rr.Body.String(), "\n"))
+}
+
+func TestGetWeatherDetailFull(t *testing.T) {
+	rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000?detail=full")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	expected := `{"temp_c":21,"temp_f":69.8,"temp_k":294.2,` +
+		`"feels_like":{"temp_c":0,"temp_f":32,"temp_k":273.2},"condition":"Overcast","is_day":false,"humidity":83,` +
+		`"wind":{"speed_kph":13,"speed_mph":8.1,"speed_mps":3.6,"gust_kph":20.2,"gust_mph":12.6,"gust_mps":5.6,"degree":110,"direction":"ESE"},` +
+		`"pressure_mb":1008,"pressure_in":29.8,"pressure_pa":100800,"precip_mm":0,"precip_in":0,` +
+		`"vis_km":10,"vis_miles":6.2,"vis_m":10000,"uv":0,"cloud":100,"observed_at":"2024-05-24T19:15:00Z",` +
+		`"address":{"cep":"88010-000","street":"Rua Felipe Schmidt","neighborhood":"Centro","city":"Florianópolis","state":"SC"}}`
+	assert.Equal(t, expected, strings.TrimRight(rr.Body.String(), "\n"))
+}
+
+func TestGetWeatherSelectedFields(t *testing.T) {
+	rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000?fields=humidity,%20Address")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	expected := `{"temp_c":21,"temp_f":69.8,"temp_k":294.2,"humidity":83,` +
+		`"address":{"cep":"88010-000","street":"Rua Felipe Schmidt","neighborhood":"Centro","city":"Florianópolis","state":"SC"}}`
+	assert.Equal(t, expected, strings.TrimRight(rr.Body.String(), "\n"))
+}
+
+func TestGetWeatherInvalidFields(t *testing.T) {
+	for _, query := range []string{"fields=humidity,moon", "detail=verbose"} {
+		rr := serveGetWeather(t, newFieldsTestHandler(), "/weather/88010000?"+query)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_parameter"`, query)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	assert := assert.New(t)
+
+	fields, err := parseFields(url.Values{"detail": {DetailBasic}, "fields": {"wind,,uv"}})
+
+	assert.Nil(err)
+	assert.Equal(map[string]bool{FieldWind: true, FieldUV: true}, fields)
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
 	"github.com/rcbadiale/go-cloud-run/internals/services"
 )
 
@@ -12,75 +15,133 @@ const (
 	InvalidZipCode      = "invalid zipcode"
 	CannotFindZipCode   = "cant find zipcode"
 	InternalServerError = "internal server error"
+	ServiceUnavailable  = "service unavailable"
+	CannotFindWeather   = "cant find weather"
+	InvalidParameter    = "invalid parameter"
+	NotImplemented      = "not implemented"
+	InvalidBody         = "invalid request body"
+	CannotFindLocation  = "cant find location"
 )
 
+// GetWeatherResponse is the current temperature, along with the optional
+// fields selected in the request, in the requested unit system
 type GetWeatherResponse struct {
-	TempC float64 `json:"temp_c"`
-	TempF float64 `json:"temp_f"`
-	TempK float64 `json:"temp_k"`
+	Temperature
+	FeelsLike  *Temperature     `json:"feels_like,omitempty"`
+	Condition  *string          `json:"condition,omitempty"`
+	IsDay      *bool            `json:"is_day,omitempty"`
+	Humidity   *int             `json:"humidity,omitempty"`
+	Wind       *WindResponse    `json:"wind,omitempty"`
+	PressureMb *float64         `json:"pressure_mb,omitempty"`
+	PressureIn *float64         `json:"pressure_in,omitempty"`
+	PressurePa *float64         `json:"pressure_pa,omitempty"`
+	PrecipMm   *float64         `json:"precip_mm,omitempty"`
+	PrecipIn   *float64         `json:"precip_in,omitempty"`
+	VisKm      *float64         `json:"vis_km,omitempty"`
+	VisMiles   *float64         `json:"vis_miles,omitempty"`
+	VisM       *float64         `json:"vis_m,omitempty"`
+	Uv         *float64         `json:"uv,omitempty"`
+	Cloud      *int             `json:"cloud,omitempty"`
+	ObservedAt *time.Time       `json:"observed_at,omitempty"`
+	Address    *AddressResponse `json:"address,omitempty"`
 }
 
 type WeatherHandler struct {
 	CEPService     services.CEPService
 	WeatherService services.WeatherService
+	// Geocoder resolves coordinates for addresses returned without them,
+	// it is optional
+	Geocoder services.Geocoder
+	// ForecastService serves the forecast endpoint, it is optional
+	ForecastService services.ForecastService
+	// HistoryService serves the history endpoint, it is optional
+	HistoryService services.HistoryService
+	// MunicipalityService resolves the IBGE codes missing from the embedded
+	// table, it is optional
+	MunicipalityService services.MunicipalityService
+	// BatchWorkers and BatchMaxItems bound the batch endpoint, defaulting to
+	// Batch_Workers and Batch_MaxItems
+	BatchWorkers  int
+	BatchMaxItems int
+	// StreamWorkers bounds the lookups in flight of a stream, defaulting to
+	// Stream_Workers
+	StreamWorkers int
 }
 
-func NewWeatherHandler(weatherApiKey string) *WeatherHandler {
+func NewWeatherHandler(cepService services.CEPService, weatherService services.WeatherService, geocoder services.Geocoder) *WeatherHandler {
 	return &WeatherHandler{
-		CEPService:     services.NewViaCEPService(),
-		WeatherService: services.NewWeatherAPIService(weatherApiKey),
+		CEPService:     cepService,
+		WeatherService: weatherService,
+		Geocoder:       geocoder,
 	}
 }
 
 // GetWeather returns the weather
 func (wh *WeatherHandler) GetWeather(w http.ResponseWriter, r *http.Request) {
-	zipCode := chi.URLParam(r, "zipCode")
-	if len(zipCode) != 8 {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		w.Write([]byte(InvalidZipCode))
+	fields, format, ok := parseWeatherOptions(w, r)
+	if !ok {
 		return
 	}
-	responseCEP, error := wh.CEPService.GetAddressByCEP(zipCode)
-	if error != nil {
-		switch error {
-		case services.ErrCEPNotFound:
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(CannotFindZipCode))
-			return
-		case services.ErrInvalidCEP:
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			w.Write([]byte(InvalidZipCode))
-			return
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(InternalServerError))
-			return
-		}
+	address, ok := resolveAddress(w, r, wh.CEPService)
+	if !ok {
+		return
 	}
-	responseWeather, error := wh.WeatherService.GetWeatherByCity(responseCEP.Localidade)
-	if error != nil {
-		switch error {
-		case services.ErrCEPNotFound:
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(CannotFindZipCode))
-			return
-		case services.ErrInvalidCEP:
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			w.Write([]byte(InvalidZipCode))
-			return
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(InternalServerError))
-			return
-		}
+	wh.writeWeather(w, r, address, wh.coordinatesFor(r.Context(), address), fields, format)
+}
+
+// resolveAddress returns the address of the request zip code from
+// cepService, writing the error response when it can not be resolved
+func resolveAddress(w http.ResponseWriter, r *http.Request, cepService services.CEPService) (*services.Address, bool) {
+	zipCode, err := cep.Parse(chi.URLParam(r, "zipCode"))
+	if err != nil {
+		writeProblem(w, r, errInvalidCEP, err.Error())
+		return nil, false
+	}
+	address, err := cepService.GetAddressByCEP(r.Context(), zipCode)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return nil, false
+	}
+	return address, true
+}
+
+// weatherForAddress looks the weather up by the address coordinates, using a
+// city, state and country query when they can not be resolved
+func (wh *WeatherHandler) weatherForAddress(ctx context.Context, address *services.Address) (*services.Weather, error) {
+	return wh.weatherAt(ctx, address, wh.coordinatesFor(ctx, address))
+}
+
+// weatherAt looks the weather up by coordinates, or by the address city,
+// state and country query when they are nil
+func (wh *WeatherHandler) weatherAt(ctx context.Context, address *services.Address, coordinates *services.Coordinates) (*services.Weather, error) {
+	return lookupAt(ctx, address, coordinates, wh.WeatherService.GetWeatherByCoordinates, wh.WeatherService.GetWeatherByCity)
+}
+
+// lookupAt calls byCoordinates when coordinates are known, or byCity with the
+// address city, state and country query when they are nil
+func lookupAt[T any](
+	ctx context.Context,
+	address *services.Address,
+	coordinates *services.Coordinates,
+	byCoordinates func(ctx context.Context, lat, lon float64) (T, error),
+	byCity func(ctx context.Context, query string) (T, error),
+) (T, error) {
+	if coordinates != nil {
+		return byCoordinates(ctx, coordinates.Lat, coordinates.Lon)
 	}
-	// Truncating values to ensure only 1 decimal place
-	output := GetWeatherResponse{
-		TempC: float64(int(responseWeather.Current.TempC*10)) / 10,
-		TempF: float64(int(responseWeather.Current.TempF*10)) / 10,
-		TempK: float64(int((responseWeather.Current.TempC+273.15)*10)) / 10,
+	return byCity(ctx, address.WeatherQuery())
+}
+
+// coordinatesFor returns the address coordinates, geocoding them when the
+// CEP provider did not return any, or nil when they can not be resolved
+func (wh *WeatherHandler) coordinatesFor(ctx context.Context, address *services.Address) *services.Coordinates {
+	coordinates := address.Coordinates
+	if coordinates == nil && wh.Geocoder != nil {
+		var err error
+		coordinates, err = wh.Geocoder.Geocode(ctx, address)
+		if err != nil {
+			log.Printf("error geocoding %s: %v\n", address.WeatherQuery(), err)
+		}
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(output)
+	return coordinates
 }
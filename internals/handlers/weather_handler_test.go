@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
 	"github.com/rcbadiale/go-cloud-run/internals/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,18 +23,32 @@ type MockViaCEPService struct {
 	mock.Mock
 }
 
-func (m *MockViaCEPService) GetAddressByCEP(cep string) (*services.ViaCEPResponse, error) {
-	args := m.Called(cep)
-	return args.Get(0).(*services.ViaCEPResponse), args.Error(1)
+func (m *MockViaCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*services.Address, error) {
+	args := m.Called(ctx, cep.Digits())
+	return args.Get(0).(*services.Address), args.Error(1)
 }
 
 type MockWeatherAPIService struct {
 	mock.Mock
 }
 
-func (m *MockWeatherAPIService) GetWeatherByCity(city string) (*services.WeatherAPIResponse, error) {
-	args := m.Called(city)
-	return args.Get(0).(*services.WeatherAPIResponse), args.Error(1)
+func (m *MockWeatherAPIService) GetWeatherByCity(ctx context.Context, city string) (*services.Weather, error) {
+	args := m.Called(ctx, city)
+	return args.Get(0).(*services.Weather), args.Error(1)
+}
+
+func (m *MockWeatherAPIService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*services.Weather, error) {
+	args := m.Called(ctx, lat, lon)
+	return args.Get(0).(*services.Weather), args.Error(1)
+}
+
+type MockGeocoder struct {
+	mock.Mock
+}
+
+func (m *MockGeocoder) Geocode(ctx context.Context, address *services.Address) (*services.Coordinates, error) {
+	args := m.Called(ctx, address)
+	return args.Get(0).(*services.Coordinates), args.Error(1)
 }
 
 func TestGetWeather(t *testing.T) {
@@ -44,18 +63,20 @@ func TestGetWeather(t *testing.T) {
 	mockViaCEPService := new(MockViaCEPService)
 	mockViaCEPService.On(
 		"GetAddressByCEP",
+		mock.Anything,
 		"12345678").Return(
-		&services.ViaCEPResponse{
-			Localidade: "TestCity",
+		&services.Address{
+			City: "TestCity",
 		}, nil,
 	)
 
 	mockWeatherService := new(MockWeatherAPIService)
 	mockWeatherService.On(
 		"GetWeatherByCity",
-		"TestCity").Return(
-		&services.WeatherAPIResponse{
-			Current: services.WeatherAPIResponseCurrent{
+		mock.Anything,
+		"TestCity, Brazil").Return(
+		&services.Weather{
+			Current: services.CurrentWeather{
 				TempC: 10.0,
 				TempF: 99.2,
 			},
@@ -76,7 +97,145 @@ func TestGetWeather(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code, "handler returned wrong status code")
 
 	// Check the response body is what we expect
-	expected := `{"temp_c":10,"temp_f":99.2,"temp_k":283.1}`
+	expected := `{"temp_c":10,"temp_f":99.2,"temp_k":283.2}`
 	assert.Equal(t, expected, strings.TrimRight(rr.Body.String(), "\n"), "handler returned unexpected body")
 	assert.Equal(t, 200, rr.Result().StatusCode, "handler returned unexpected statusCode")
 }
+
+func TestGetWeatherByAddressCoordinates(t *testing.T) {
+	mockViaCEPService := new(MockViaCEPService)
+	mockViaCEPService.On("GetAddressByCEP", mock.Anything, "59200000").Return(
+		&services.Address{
+			City:        "Santa Cruz",
+			State:       "RN",
+			Coordinates: &services.Coordinates{Lat: -6.22944, Lon: -36.02278},
+		}, nil,
+	)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, -6.22944, -36.02278).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 28.0, TempF: 82.4}}, nil,
+	)
+
+	rr := serveGetWeather(t, &WeatherHandler{
+		CEPService:     mockViaCEPService,
+		WeatherService: mockWeatherService,
+	}, "/weather/59200000")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"temp_c":28,"temp_f":82.4,"temp_k":301.2}`, strings.TrimRight(rr.Body.String(), "\n"))
+	mockWeatherService.AssertNotCalled(t, "GetWeatherByCity", mock.Anything, mock.Anything)
+}
+
+func TestGetWeatherByGeocodedCoordinates(t *testing.T) {
+	address := &services.Address{City: "Santa Cruz", State: "PE"}
+	mockViaCEPService := new(MockViaCEPService)
+	mockViaCEPService.On("GetAddressByCEP", mock.Anything, "56215000").Return(address, nil)
+	mockGeocoder := new(MockGeocoder)
+	mockGeocoder.On("Geocode", mock.Anything, address).Return(
+		&services.Coordinates{Lat: -8.24167, Lon: -40.33444}, nil,
+	)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, -8.24167, -40.33444).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 30.0, TempF: 86.0}}, nil,
+	)
+
+	rr := serveGetWeather(t, &WeatherHandler{
+		CEPService:     mockViaCEPService,
+		WeatherService: mockWeatherService,
+		Geocoder:       mockGeocoder,
+	}, "/weather/56215000")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"temp_c":30,"temp_f":86,"temp_k":303.2}`, strings.TrimRight(rr.Body.String(), "\n"))
+}
+
+func TestGetWeatherFallsBackToCityQuery(t *testing.T) {
+	address := &services.Address{City: "Santa Cruz", State: "RN"}
+	mockViaCEPService := new(MockViaCEPService)
+	mockViaCEPService.On("GetAddressByCEP", mock.Anything, "59200000").Return(address, nil)
+	mockGeocoder := new(MockGeocoder)
+	mockGeocoder.On("Geocode", mock.Anything, address).Return(
+		(*services.Coordinates)(nil), services.ErrLocationNotFound,
+	)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, "Santa Cruz, Rio Grande do Norte, Brazil").Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 28.0, TempF: 82.4}}, nil,
+	)
+
+	rr := serveGetWeather(t, &WeatherHandler{
+		CEPService:     mockViaCEPService,
+		WeatherService: mockWeatherService,
+		Geocoder:       mockGeocoder,
+	}, "/weather/59200000")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockWeatherService.AssertExpectations(t)
+}
+
+// serve routes a request for path with body, if any, to handlerFunc mounted
+// on pattern
+func serve(t *testing.T, method, pattern string, handlerFunc http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r := chi.NewRouter()
+	r.Method(method, pattern, handlerFunc)
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+// serveGetWeather routes a GET request for path to the handler
+func serveGetWeather(t *testing.T, handler *WeatherHandler, path string) *httptest.ResponseRecorder {
+	return serve(t, "GET", "/weather/{zipCode}", handler.GetWeather, path, "")
+}
+
+func TestGetWeatherCircuitOpen(t *testing.T) {
+	mockViaCEPService := new(MockViaCEPService)
+	mockViaCEPService.On("GetAddressByCEP", mock.Anything, "01001000").Return(
+		(*services.Address)(nil),
+		fmt.Errorf("%w: %w", services.ErrCEPServiceUnavailable, &internals.CircuitOpenError{Name: "viacep", RetryAfter: 2500 * time.Millisecond}),
+	)
+
+	rr := serveGetWeather(t, &WeatherHandler{CEPService: mockViaCEPService}, "/weather/01001000")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("Retry-After"))
+	assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `"code":"upstream_unavailable"`)
+}
+
+func TestGetWeatherNormalizesCEP(t *testing.T) {
+	mockViaCEPService := new(MockViaCEPService)
+	mockViaCEPService.On("GetAddressByCEP", mock.Anything, "01310100").Return(&services.Address{City: "São Paulo", State: "SP"}, nil)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, mock.Anything).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 20.0, TempF: 68.0}}, nil,
+	)
+	handler := &WeatherHandler{CEPService: mockViaCEPService, WeatherService: mockWeatherService}
+
+	for _, path := range []string{"/weather/01310-100", "/weather/01.310-100", "/weather/01310%20100"} {
+		rr := serveGetWeather(t, handler, path)
+
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+	}
+	mockViaCEPService.AssertNumberOfCalls(t, "GetAddressByCEP", 3)
+}
+
+func TestGetWeatherInvalidCEP(t *testing.T) {
+	mockViaCEPService := new(MockViaCEPService)
+	for path, detail := range map[string]string{
+		"/weather/abcdefgh":  "the CEP must only have digits, optionally separated by '-', '.' or spaces",
+		"/weather/0131010":   "the CEP must have 8 digits",
+		"/weather/00000000":  "the CEP is not in the range of any state",
+		"/weather/00999-999": "the CEP is not in the range of any state",
+	} {
+		rr := serveGetWeather(t, &WeatherHandler{CEPService: mockViaCEPService}, path)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, path)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_cep"`, path)
+		assert.Contains(t, rr.Body.String(), detail, path)
+	}
+	mockViaCEPService.AssertNotCalled(t, "GetAddressByCEP", mock.Anything, mock.Anything)
+}
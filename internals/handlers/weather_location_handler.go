@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rcbadiale/go-cloud-run/internals/ibge"
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+)
+
+// GetWeatherByCity returns the weather of the city called name in uf,
+// located through the IBGE municipality table, the Geocoder or, when neither
+// knows it, a city, state and country query
+func (wh *WeatherHandler) GetWeatherByCity(w http.ResponseWriter, r *http.Request) {
+	fields, format, ok := parseWeatherOptions(w, r)
+	if !ok {
+		return
+	}
+	uf := strings.ToUpper(chi.URLParam(r, "uf"))
+	if _, ok := services.StateNames[uf]; !ok {
+		writeProblem(w, r, errInvalidParameter, fmt.Sprintf("unknown uf %q, expected the 2 letter code of a state", chi.URLParam(r, "uf")))
+		return
+	}
+	name := strings.TrimSpace(pathParam(r, "name"))
+	if name == "" {
+		writeProblem(w, r, errInvalidParameter, "the city name must not be empty")
+		return
+	}
+	address := &services.Address{City: name, State: uf}
+	if municipality, ok := ibge.FindByName(uf, name); ok {
+		address = municipalityAddress(municipality)
+	}
+	wh.writeWeather(w, r, address, wh.coordinatesFor(r.Context(), address), fields, format)
+}
+
+// GetWeatherByCoordinates returns the weather at the lat and lon query
+// parameters, in decimal degrees
+func (wh *WeatherHandler) GetWeatherByCoordinates(w http.ResponseWriter, r *http.Request) {
+	fields, format, ok := parseWeatherOptions(w, r)
+	if !ok {
+		return
+	}
+	coordinates, err := parseCoordinates(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return
+	}
+	wh.writeWeather(w, r, nil, coordinates, fields, format)
+}
+
+// GetWeatherByIBGE returns the weather of the municipality with the IBGE code
+// of the request, resolving the codes missing from the embedded table with
+// the MunicipalityService and locating them as GetWeatherByCity does
+func (wh *WeatherHandler) GetWeatherByIBGE(w http.ResponseWriter, r *http.Request) {
+	fields, format, ok := parseWeatherOptions(w, r)
+	if !ok {
+		return
+	}
+	code := chi.URLParam(r, "code")
+	if _, err := strconv.Atoi(code); err != nil || len(code) != 7 {
+		writeProblem(w, r, errInvalidParameter, "the IBGE code must have 7 digits")
+		return
+	}
+	if municipality, ok := ibge.Lookup(code); ok {
+		address := municipalityAddress(municipality)
+		wh.writeWeather(w, r, address, address.Coordinates, fields, format)
+		return
+	} else if wh.MunicipalityService == nil {
+		writeProblem(w, r, errLocationNotFound, "no municipality has the IBGE code")
+		return
+	}
+	address, err := wh.MunicipalityService.GetMunicipality(r.Context(), code)
+	if errors.Is(err, services.ErrLocationNotFound) {
+		writeProblem(w, r, errLocationNotFound, "no municipality has the IBGE code")
+		return
+	} else if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	wh.writeWeather(w, r, address, wh.coordinatesFor(r.Context(), address), fields, format)
+}
+
+// pathParam returns the decoded URL parameter key. chi matches routes on the
+// already decoded path, unless it has characters such as an encoded slash
+// that only the raw path keeps, leaving its parameters escaped
+func pathParam(r *http.Request, key string) string {
+	value := chi.URLParam(r, key)
+	if r.URL.RawPath == "" {
+		return value
+	}
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+// parseWeatherOptions returns the optional fields and the output format of
+// the request, writing the error response when they are not valid
+func parseWeatherOptions(w http.ResponseWriter, r *http.Request) (map[string]bool, outputFormat, bool) {
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return nil, outputFormat{}, false
+	}
+	format, err := parseOutputFormat(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, errInvalidParameter, err.Error())
+		return nil, outputFormat{}, false
+	}
+	return fields, format, true
+}
+
+// writeWeather writes the weather looked up as GetWeather does, at
+// coordinates or by the address query when they are nil
+func (wh *WeatherHandler) writeWeather(w http.ResponseWriter, r *http.Request, address *services.Address, coordinates *services.Coordinates, fields map[string]bool, format outputFormat) {
+	weather, err := wh.weatherAt(r.Context(), address, coordinates)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWeatherResponse(address, weather, fields, format))
+}
+
+// parseCoordinates parses the required lat and lon query parameters
+func parseCoordinates(query url.Values) (*services.Coordinates, error) {
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid lat %q, expected a latitude from -90 to 90", query.Get("lat"))
+	}
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("invalid lon %q, expected a longitude from -180 to 180", query.Get("lon"))
+	}
+	return &services.Coordinates{Lat: lat, Lon: lon}, nil
+}
+
+// municipalityAddress returns the address of a municipality, located at its
+// coordinates
+func municipalityAddress(municipality ibge.Municipality) *services.Address {
+	return &services.Address{
+		City:        municipality.Name,
+		State:       municipality.UF,
+		Ibge:        municipality.Code,
+		Coordinates: &services.Coordinates{Lat: municipality.Lat, Lon: municipality.Lon},
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// locationRequest is a request to one of the weather handlers that skip CEP
+// resolution, mounted on pattern
+type locationRequest struct {
+	pattern string
+	handle  http.HandlerFunc
+	path    string
+}
+
+type MockMunicipalityService struct {
+	mock.Mock
+}
+
+func (m *MockMunicipalityService) GetMunicipality(ctx context.Context, code string) (*services.Address, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(*services.Address), args.Error(1)
+}
+
+// newLocationTestHandler answers 20°C at the Florianópolis coordinates of
+// the IBGE table, and for the CEP 88010000
+func newLocationTestHandler() (*WeatherHandler, *MockWeatherAPIService) {
+	mockCEPService := new(MockViaCEPService)
+	mockCEPService.On("GetAddressByCEP", mock.Anything, "88010000").Return(&services.Address{
+		Cep: "88010-000", City: "Florianópolis", State: "SC", Coordinates: &services.Coordinates{Lat: -27.5945, Lon: -48.5477},
+	}, nil)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, -27.5945, -48.5477).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 20, TempF: 68, Humidity: 80}}, nil,
+	)
+	return &WeatherHandler{CEPService: mockCEPService, WeatherService: mockWeatherService}, mockWeatherService
+}
+
+func TestGetWeatherByLocationSameShape(t *testing.T) {
+	handler, _ := newLocationTestHandler()
+	for _, request := range []locationRequest{
+		{"/weather/{zipCode}", handler.GetWeather, "/weather/88010000?fields=humidity&units=metric"},
+		{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/sc/Florian%C3%B3polis?fields=humidity&units=metric"},
+		{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SC/florianopolis?fields=humidity&units=metric"},
+		{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/sc/florian%c3%b3polis?fields=humidity&units=metric"},
+		{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=-27.5945&lon=-48.5477&fields=humidity&units=metric"},
+		{"/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/4205407?fields=humidity&units=metric"},
+	} {
+		rr := serve(t, "GET", request.pattern, request.handle, request.path, "")
+
+		assert.Equal(t, http.StatusOK, rr.Code, request.path)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), request.path)
+		assert.Equal(t, `{"temp_c":20,"humidity":80}`, strings.TrimRight(rr.Body.String(), "\n"), request.path)
+	}
+}
+
+func TestGetWeatherByCityAddress(t *testing.T) {
+	handler, _ := newLocationTestHandler()
+
+	rr := serve(t, "GET", "/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SC/florianopolis?fields=address&units=si", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"temp_k":293.2,"address":{"cep":"","street":"","neighborhood":"","city":"Florianópolis","state":"SC"}}`, strings.TrimRight(rr.Body.String(), "\n"))
+}
+
+func TestGetWeatherByCityFallsBackToCityQuery(t *testing.T) {
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, "Vila Nova, São Paulo, Brazil").Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 25, TempF: 77}}, nil,
+	)
+
+	handler := &WeatherHandler{WeatherService: mockWeatherService}
+	rr := serve(t, "GET", "/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SP/Vila%20Nova", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockWeatherService.AssertExpectations(t)
+}
+
+func TestGetWeatherByCityDecodesNameOnce(t *testing.T) {
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCity", mock.Anything, mock.Anything).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 25, TempF: 77}}, nil,
+	)
+	handler := &WeatherHandler{WeatherService: mockWeatherService}
+	for path, query := range map[string]string{
+		"/weather/city/SP/100%25":       "100%, São Paulo, Brazil",
+		"/weather/city/SP/Vila%2525":    "Vila%25, São Paulo, Brazil",
+		"/weather/city/SP/Vila%2FNova":  "Vila/Nova, São Paulo, Brazil",
+		"/weather/city/SP/Vila%2f%2525": "Vila/%25, São Paulo, Brazil",
+	} {
+		rr := serve(t, "GET", "/weather/city/{uf}/{name}", handler.GetWeatherByCity, path, "")
+
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		mockWeatherService.AssertCalled(t, "GetWeatherByCity", mock.Anything, query)
+	}
+}
+
+func TestGetWeatherByLocationErrors(t *testing.T) {
+	handler, mockWeatherService := newLocationTestHandler()
+	for _, test := range []struct {
+		request locationRequest
+		code    string
+	}{
+		{locationRequest{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/XX/Campinas"}, CodeInvalidParameter},
+		{locationRequest{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SP/%20"}, CodeInvalidParameter},
+		{locationRequest{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SP/Campinas?units=nautical"}, CodeInvalidParameter},
+		{locationRequest{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=-27.59"}, CodeInvalidParameter},
+		{locationRequest{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=91&lon=0"}, CodeInvalidParameter},
+		{locationRequest{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=0&lon=-180.5"}, CodeInvalidParameter},
+		{locationRequest{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=NaN&lon=0"}, CodeInvalidParameter},
+		{locationRequest{"/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/42054"}, CodeInvalidParameter},
+		{locationRequest{"/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/abcdefg"}, CodeInvalidParameter},
+		{locationRequest{"/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/1234567"}, CodeLocationNotFound},
+		{locationRequest{"/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/4205407?fields=unknown"}, CodeInvalidParameter},
+		{locationRequest{"/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=0&lon=0&precision=9"}, CodeInvalidParameter},
+		{locationRequest{"/weather/city/{uf}/{name}", handler.GetWeatherByCity, "/weather/city/SC/Florian%C3%B3polis?detail=nope"}, CodeInvalidParameter},
+	} {
+		rr := serve(t, "GET", test.request.pattern, test.request.handle, test.request.path, "")
+
+		assert.Contains(t, rr.Body.String(), `"code":"`+test.code+`"`, test.request.path)
+	}
+	mockWeatherService.AssertNotCalled(t, "GetWeatherByCoordinates", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetWeatherByCoordinatesUpstreamError(t *testing.T) {
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, 0.0, 0.0).Return(
+		(*services.Weather)(nil), &services.UpstreamError{Service: "weatherapi", Kind: services.ErrLocationNotFound, Code: 1006},
+	)
+
+	handler := &WeatherHandler{WeatherService: mockWeatherService}
+	rr := serve(t, "GET", "/weather/coords", handler.GetWeatherByCoordinates, "/weather/coords?lat=0&lon=0", "")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"weather_not_found"`)
+}
+
+func TestGetWeatherByIBGEMissingFromTable(t *testing.T) {
+	address := &services.Address{City: "São Gonçalo", State: "RJ", Ibge: "3304904", Provider: services.ProviderIBGE}
+	mockMunicipalityService := new(MockMunicipalityService)
+	mockMunicipalityService.On("GetMunicipality", mock.Anything, "3304904").Return(address, nil)
+	mockMunicipalityService.On("GetMunicipality", mock.Anything, "1234567").Return((*services.Address)(nil), services.ErrLocationNotFound)
+	mockMunicipalityService.On("GetMunicipality", mock.Anything, "7654321").Return(
+		(*services.Address)(nil), &services.UpstreamError{Service: services.ProviderIBGE, Kind: services.ErrUpstreamUnavailable},
+	)
+	mockGeocoder := new(MockGeocoder)
+	mockGeocoder.On("Geocode", mock.Anything, address).Return(&services.Coordinates{Lat: -22.8268, Lon: -43.0634}, nil)
+	mockWeatherService := new(MockWeatherAPIService)
+	mockWeatherService.On("GetWeatherByCoordinates", mock.Anything, -22.8268, -43.0634).Return(
+		&services.Weather{Current: services.CurrentWeather{TempC: 27, TempF: 80.6}}, nil,
+	)
+	handler := &WeatherHandler{WeatherService: mockWeatherService, Geocoder: mockGeocoder, MunicipalityService: mockMunicipalityService}
+
+	rr := serve(t, "GET", "/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/3304904?fields=address&units=metric", "")
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Equal(`{"temp_c":27,"address":{"cep":"","street":"","neighborhood":"","city":"São Gonçalo","state":"RJ"}}`, strings.TrimRight(rr.Body.String(), "\n"))
+
+	rr = serve(t, "GET", "/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/1234567", "")
+	assert.Equal(http.StatusNotFound, rr.Code)
+	assert.Contains(rr.Body.String(), `"code":"location_not_found"`)
+
+	rr = serve(t, "GET", "/weather/ibge/{code}", handler.GetWeatherByIBGE, "/weather/ibge/7654321", "")
+	assert.Equal(http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,99 @@
+//go:build ignore
+
+// generate rebuilds municipios.csv from the municipality and state tables of
+// github.com/kelvins/municipios-brasileiros, which carry the IBGE codes along
+// with the coordinates and timezone of every municipality.
+//
+//	go generate ./internals/ibge
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+)
+
+const (
+	municipiosURL = "https://raw.githubusercontent.com/kelvins/municipios-brasileiros/main/csv/municipios.csv"
+	estadosURL    = "https://raw.githubusercontent.com/kelvins/municipios-brasileiros/main/csv/estados.csv"
+	// minMunicipalities guards against writing a truncated download
+	minMunicipalities = 5570
+)
+
+func main() {
+	states, err := fetch(estadosURL)
+	if err != nil {
+		log.Fatalln("error fetching states: ", err)
+	}
+	ufs := map[string]string{}
+	for _, state := range states {
+		ufs[state["codigo_uf"]] = state["uf"]
+	}
+
+	municipalities, err := fetch(municipiosURL)
+	if err != nil {
+		log.Fatalln("error fetching municipalities: ", err)
+	}
+	if len(municipalities) < minMunicipalities {
+		log.Fatalf("expected at least %d municipalities, got %d\n", minMunicipalities, len(municipalities))
+	}
+	sort.Slice(municipalities, func(i, j int) bool {
+		return municipalities[i]["codigo_ibge"] < municipalities[j]["codigo_ibge"]
+	})
+
+	file, err := os.Create("municipios.csv")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"codigo_ibge", "nome", "uf", "latitude", "longitude", "fuso_horario"})
+	for _, municipality := range municipalities {
+		uf, ok := ufs[municipality["codigo_uf"]]
+		if !ok {
+			log.Fatalf("unknown codigo_uf %s of %s\n", municipality["codigo_uf"], municipality["codigo_ibge"])
+		}
+		writer.Write([]string{
+			municipality["codigo_ibge"],
+			municipality["nome"],
+			uf,
+			municipality["latitude"],
+			municipality["longitude"],
+			municipality["fuso_horario"],
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// fetch downloads a CSV table, returning its rows keyed by the header
+func fetch(url string) ([]map[string]string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	} else if len(records) == 0 {
+		return nil, fmt.Errorf("%s: missing header", url)
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(record))
+		for i, name := range records[0] {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
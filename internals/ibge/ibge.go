@@ -0,0 +1,140 @@
+// Package ibge provides offline lookups of Brazilian municipalities keyed by
+// their IBGE code, backed by a table embedded in the binary.
+//
+// municipios.csv keeps the layout of the IBGE municipality table:
+// codigo_ibge,nome,uf,latitude,longitude,fuso_horario. Refreshing the data
+// only requires replacing that file, which go generate rebuilds with every
+// municipality.
+package ibge
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run generate.go
+
+//go:embed municipios.csv
+var municipiosCSV string
+
+// Municipality is a Brazilian municipality as listed by IBGE
+type Municipality struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	UF       string  `json:"uf"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Timezone string  `json:"timezone"`
+}
+
+// Dataset indexes municipalities by IBGE code and by UF and name
+type Dataset struct {
+	byCode map[string]Municipality
+	byName map[string]Municipality
+}
+
+var embedded = sync.OnceValue(func() *Dataset {
+	dataset, err := Parse(strings.NewReader(municipiosCSV))
+	if err != nil {
+		panic(fmt.Sprintf("ibge: invalid embedded dataset: %v", err))
+	}
+	return dataset
+})
+
+// Lookup returns the municipality for an IBGE code from the embedded dataset
+func Lookup(code string) (Municipality, bool) {
+	return embedded().Lookup(code)
+}
+
+// FindByName returns the municipality called name in uf from the embedded
+// dataset, ignoring case and accents
+func FindByName(uf, name string) (Municipality, bool) {
+	return embedded().FindByName(uf, name)
+}
+
+// Len returns the number of municipalities in the embedded dataset
+func Len() int {
+	return embedded().Len()
+}
+
+// Parse reads a municipality table in the municipios.csv layout
+func Parse(r io.Reader) (*Dataset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 6
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	dataset := &Dataset{
+		byCode: make(map[string]Municipality, len(records)-1),
+		byName: make(map[string]Municipality, len(records)-1),
+	}
+	for i, record := range records[1:] {
+		lat, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %w", i+2, err)
+		}
+		lon, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %w", i+2, err)
+		}
+		municipality := Municipality{
+			Code:     record[0],
+			Name:     record[1],
+			UF:       strings.ToUpper(record[2]),
+			Lat:      lat,
+			Lon:      lon,
+			Timezone: record[5],
+		}
+		if _, ok := dataset.byCode[municipality.Code]; ok {
+			return nil, fmt.Errorf("line %d: duplicated IBGE code %s", i+2, municipality.Code)
+		}
+		dataset.byCode[municipality.Code] = municipality
+		dataset.byName[nameKey(municipality.UF, municipality.Name)] = municipality
+	}
+	return dataset, nil
+}
+
+// Lookup returns the municipality for an IBGE code
+func (d *Dataset) Lookup(code string) (Municipality, bool) {
+	municipality, ok := d.byCode[strings.TrimSpace(code)]
+	return municipality, ok
+}
+
+// FindByName returns the municipality called name in uf, ignoring case and
+// accents
+func (d *Dataset) FindByName(uf, name string) (Municipality, bool) {
+	municipality, ok := d.byName[nameKey(uf, name)]
+	return municipality, ok
+}
+
+// Len returns the number of municipalities in the dataset
+func (d *Dataset) Len() int {
+	return len(d.byCode)
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e", "ë", "e",
+	"í", "i", "î", "i", "ì", "i", "ï", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ò", "o", "ö", "o",
+	"ú", "u", "û", "u", "ù", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// Fold lowercases value and strips its Portuguese accents
+func Fold(value string) string {
+	return accents.Replace(strings.ToLower(strings.TrimSpace(value)))
+}
+
+func nameKey(uf, name string) string {
+	return strings.ToUpper(strings.TrimSpace(uf)) + "/" + Fold(name)
+}
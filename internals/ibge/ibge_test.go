@@ -0,0 +1,79 @@
+package ibge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	municipality, ok := Lookup("3550308")
+
+	assert := assert.New(t)
+	assert.True(ok)
+	assert.Equal("São Paulo", municipality.Name)
+	assert.Equal("SP", municipality.UF)
+	assert.Equal(-23.5329, municipality.Lat)
+	assert.Equal(-46.6395, municipality.Lon)
+	assert.Equal("America/Sao_Paulo", municipality.Timezone)
+
+	_, ok = Lookup("0000000")
+	assert.False(ok)
+}
+
+func TestFindByName(t *testing.T) {
+	assert := assert.New(t)
+
+	municipality, ok := FindByName("sc", "FLORIANOPOLIS")
+	assert.True(ok)
+	assert.Equal("4205407", municipality.Code)
+
+	_, ok = FindByName("SP", "Florianópolis")
+	assert.False(ok)
+}
+
+func TestEmbeddedDataset(t *testing.T) {
+	assert := assert.New(t)
+	assert.Greater(Len(), 0)
+
+	for _, code := range []string{"1100205", "2927408", "4314902", "5300108"} {
+		municipality, ok := Lookup(code)
+		assert.True(ok, code)
+		assert.NotEmpty(municipality.Timezone, code)
+		assert.InDelta(-15, municipality.Lat, 20, code)
+		assert.InDelta(-50, municipality.Lon, 20, code)
+	}
+}
+
+func TestEmbeddedDatasetNonCapitals(t *testing.T) {
+	assert := assert.New(t)
+	for code, name := range map[string]string{"3509502": "Campinas", "3518800": "Guarulhos", "3538709": "Piracicaba"} {
+		municipality, ok := Lookup(code)
+		assert.True(ok, code)
+		assert.Equal(name, municipality.Name, code)
+		assert.Equal("SP", municipality.UF, code)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	header := "codigo_ibge,nome,uf,latitude,longitude,fuso_horario\n"
+
+	tests := map[string]string{
+		"empty":      "",
+		"columns":    header + "3550308,São Paulo,SP\n",
+		"latitude":   header + "3550308,São Paulo,SP,north,-46.6,America/Sao_Paulo\n",
+		"longitude":  header + "3550308,São Paulo,SP,-23.5,west,America/Sao_Paulo\n",
+		"duplicated": header + "3550308,São Paulo,SP,-23.5,-46.6,America/Sao_Paulo\n3550308,São Paulo,SP,-23.5,-46.6,America/Sao_Paulo\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "sao joao del-rei", Fold(" São João del-Rei "))
+}
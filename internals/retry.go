@@ -0,0 +1,124 @@
+package internals
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	Retry_MaxAttempts = 3
+	Retry_BaseDelay   = 100 * time.Millisecond
+	Retry_MaxDelay    = 2 * time.Second
+)
+
+// RetrySettings configures a RetryClient
+type RetrySettings struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry, doubled on each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the Retry-After accepted from upstream
+	MaxDelay time.Duration
+}
+
+// DefaultRetrySettings returns the settings used when none are configured
+func DefaultRetrySettings() RetrySettings {
+	return RetrySettings{
+		MaxAttempts: Retry_MaxAttempts,
+		BaseDelay:   Retry_BaseDelay,
+		MaxDelay:    Retry_MaxDelay,
+	}
+}
+
+// RetryClient retries idempotent requests failing with transport errors,
+// 429 or 5xx, using exponential backoff with jitter. An upstream Retry-After
+// is honoured, and no retry is made when it would not fit in the request
+// context deadline. Any other response is a definitive answer and is
+// returned as is.
+type RetryClient struct {
+	Client   HTTPClient
+	Settings RetrySettings
+	jitter   func() float64
+}
+
+// NewRetryClient creates a new RetryClient
+func NewRetryClient(client HTTPClient, settings RetrySettings) *RetryClient {
+	return &RetryClient{Client: client, Settings: settings, jitter: rand.Float64}
+}
+
+// Do sends the request, retrying it while it fails with a retryable error
+func (c *RetryClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return c.Client.Do(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.Client.Do(req)
+		if attempt >= c.Settings.MaxAttempts || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if retryAfter > c.Settings.MaxDelay {
+					return resp, err
+				}
+				delay = retryAfter
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the given retry: half of it grows
+// exponentially and the other half is random, so clients spread out
+func (c *RetryClient) backoff(attempt int) time.Duration {
+	delay := c.Settings.BaseDelay << (attempt - 1)
+	if delay > c.Settings.MaxDelay || delay <= 0 {
+		delay = c.Settings.MaxDelay
+	}
+	return delay/2 + time.Duration(c.jitter()*float64(delay/2))
+}
+
+// retryable reports whether a request that got resp or err may be retried
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, ErrCircuitOpen)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as a date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,192 @@
+package internals
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSequenceServer answers each request with the next status of statuses,
+// repeating the last one, and counts the requests received
+func newSequenceServer(t *testing.T, hits *int32, header http.Header, statuses ...int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := int(atomic.AddInt32(hits, 1))
+		status := statuses[min(hit, len(statuses))-1]
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(http.StatusText(status)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestRetryClient(client HTTPClient) *RetryClient {
+	retry := NewRetryClient(client, RetrySettings{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    50 * time.Millisecond,
+	})
+	retry.jitter = func() float64 { return 0 }
+	return retry
+}
+
+func get(t *testing.T, client HTTPClient, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.Do(req)
+}
+
+func TestRetryClientRetriesServerErrors(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, nil, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+
+	resp, err := get(t, newTestRetryClient(server.Client()), server.URL)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(int32(3), hits)
+}
+
+func TestRetryClientGivesUpAfterMaxAttempts(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, nil, http.StatusInternalServerError)
+
+	resp, err := get(t, newTestRetryClient(server.Client()), server.URL)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal("Internal Server Error", string(body))
+	assert.Equal(int32(3), hits)
+}
+
+func TestRetryClientDoesNotRetryDefinitiveAnswers(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusUnauthorized} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var hits int32
+			server := newSequenceServer(t, &hits, nil, status)
+
+			resp, err := get(t, newTestRetryClient(server.Client()), server.URL)
+
+			assert.Nil(t, err)
+			assert.Equal(t, status, resp.StatusCode)
+			assert.Equal(t, int32(1), hits)
+		})
+	}
+}
+
+func TestRetryClientHonoursRetryAfter(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, http.Header{"Retry-After": {"0"}}, http.StatusTooManyRequests, http.StatusOK)
+
+	resp, err := get(t, newTestRetryClient(server.Client()), server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestRetryClientRetryAfterTooLong(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, http.Header{"Retry-After": {"120"}}, http.StatusTooManyRequests, http.StatusOK)
+
+	resp, err := get(t, newTestRetryClient(server.Client()), server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestRetryClientRespectsDeadline(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, nil, http.StatusServiceUnavailable, http.StatusOK)
+	client := newTestRetryClient(server.Client())
+	client.Settings.BaseDelay = time.Second
+	client.Settings.MaxDelay = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestRetryClientRetriesTransportErrors(t *testing.T) {
+	upstream := &stubClient{err: errors.New("connection reset by peer")}
+	client := newTestRetryClient(upstream)
+
+	_, err := get(t, client, "http://upstream/")
+
+	assert.EqualError(t, err, "connection reset by peer")
+	assert.Equal(t, 3, upstream.calls)
+}
+
+func TestRetryClientDoesNotRetryOpenCircuit(t *testing.T) {
+	upstream := &stubClient{err: &CircuitOpenError{Name: "upstream", RetryAfter: time.Second}}
+	client := newTestRetryClient(upstream)
+
+	_, err := get(t, client, "http://upstream/")
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 1, upstream.calls)
+}
+
+func TestRetryClientDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	var hits int32
+	server := newSequenceServer(t, &hits, nil, http.StatusServiceUnavailable)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	resp, err := newTestRetryClient(server.Client()).Do(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestRetryClientBackoff(t *testing.T) {
+	client := NewRetryClient(nil, RetrySettings{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+	client.jitter = func() float64 { return 1 }
+
+	assert := assert.New(t)
+	assert.Equal(100*time.Millisecond, client.backoff(1))
+	assert.Equal(200*time.Millisecond, client.backoff(2))
+	assert.Equal(400*time.Millisecond, client.backoff(3))
+	assert.Equal(time.Second, client.backoff(5))
+	assert.Equal(time.Second, client.backoff(70))
+
+	client.jitter = func() float64 { return 0 }
+	assert.Equal(50*time.Millisecond, client.backoff(1))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	delay, ok := parseRetryAfter("3")
+	assert.True(ok)
+	assert.Equal(3*time.Second, delay)
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(ok)
+	assert.InDelta(time.Hour, delay, float64(2*time.Second))
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(ok)
+	_, ok = parseRetryAfter("")
+	assert.False(ok)
+}
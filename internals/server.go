@@ -0,0 +1,116 @@
+package internals
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	Server_Port              = 8080
+	Server_ReadHeaderTimeout = 10 * time.Second
+	Server_ReadTimeout       = 30 * time.Second
+	Server_WriteTimeout      = 60 * time.Second
+	Server_IdleTimeout       = 120 * time.Second
+	Server_MaxHeaderBytes    = http.DefaultMaxHeaderBytes
+	Server_ShutdownTimeout   = 8 * time.Second
+)
+
+// ServerSettings configures the HTTP server
+type ServerSettings struct {
+	// Port is the TCP port listened on all interfaces
+	Port int
+	// ReadHeaderTimeout limits how long reading the request headers takes
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout limits how long reading the whole request takes
+	ReadTimeout time.Duration
+	// WriteTimeout limits how long a request takes from the end of its
+	// headers until the response is written
+	WriteTimeout time.Duration
+	// IdleTimeout limits how long a keep-alive connection waits for the next
+	// request
+	IdleTimeout time.Duration
+	// MaxHeaderBytes limits the size of the request headers
+	MaxHeaderBytes int
+	// ShutdownTimeout is the grace period in-flight requests have to finish
+	// once a shutdown starts
+	ShutdownTimeout time.Duration
+}
+
+// DefaultServerSettings returns the settings used when none are configured
+func DefaultServerSettings() ServerSettings {
+	return ServerSettings{
+		Port:              Server_Port,
+		ReadHeaderTimeout: Server_ReadHeaderTimeout,
+		ReadTimeout:       Server_ReadTimeout,
+		WriteTimeout:      Server_WriteTimeout,
+		IdleTimeout:       Server_IdleTimeout,
+		MaxHeaderBytes:    Server_MaxHeaderBytes,
+		ShutdownTimeout:   Server_ShutdownTimeout,
+	}
+}
+
+// Server is an http.Server that shuts down gracefully
+type Server struct {
+	http.Server
+	Settings ServerSettings
+}
+
+// NewServer creates a new Server for handler
+func NewServer(handler http.Handler, settings ServerSettings) *Server {
+	return &Server{
+		Server: http.Server{
+			Addr:              net.JoinHostPort("", strconv.Itoa(settings.Port)),
+			Handler:           handler,
+			ReadHeaderTimeout: settings.ReadHeaderTimeout,
+			ReadTimeout:       settings.ReadTimeout,
+			WriteTimeout:      settings.WriteTimeout,
+			IdleTimeout:       settings.IdleTimeout,
+			MaxHeaderBytes:    settings.MaxHeaderBytes,
+		},
+		Settings: settings,
+	}
+}
+
+// Run listens on the configured port and serves until ctx is done, then
+// shuts down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the connections of listener until ctx is done. It then stops
+// accepting connections and waits up to ShutdownTimeout for the in-flight
+// requests, closing the connections still active after it
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %s for in-flight requests\n", s.Settings.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.Settings.ShutdownTimeout)
+	defer cancel()
+	err := s.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Println("error shutting down gracefully, closing connections: ", err)
+		s.Close()
+	}
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package internals
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startTestServer serves handler on a local port until the returned cancel
+// is called, sending the result of Serve to the returned channel
+func startTestServer(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := DefaultServerSettings()
+	settings.ShutdownTimeout = shutdownTimeout
+	server := NewServer(handler, settings)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, listener)
+	}()
+	return "http://" + listener.Addr().String(), cancel, served
+}
+
+// blockingHandler answers once release is closed, signalling on started
+// when a request arrives
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte("done"))
+	})
+}
+
+type testResponse struct {
+	body string
+	err  error
+}
+
+// getAsync sends a GET request to url, sending its outcome to the returned
+// channel
+func getAsync(url string) <-chan testResponse {
+	responses := make(chan testResponse, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- testResponse{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- testResponse{string(body), err}
+	}()
+	return responses
+}
+
+func TestNewServerSettings(t *testing.T) {
+	settings := ServerSettings{
+		Port:              9090,
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    4096,
+		ShutdownTimeout:   5 * time.Second,
+	}
+
+	server := NewServer(http.NotFoundHandler(), settings)
+
+	assert := assert.New(t)
+	assert.Equal(":9090", server.Addr)
+	assert.Equal(time.Second, server.ReadHeaderTimeout)
+	assert.Equal(2*time.Second, server.ReadTimeout)
+	assert.Equal(3*time.Second, server.WriteTimeout)
+	assert.Equal(4*time.Second, server.IdleTimeout)
+	assert.Equal(4096, server.MaxHeaderBytes)
+	assert.Equal(settings, server.Settings)
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	url, shutdown, served := startTestServer(t, blockingHandler(started, release), time.Second)
+
+	response := getAsync(url)
+	<-started
+	shutdown()
+
+	// New connections are refused while the in-flight request is drained
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", url[len("http://"):])
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("server stopped before the in-flight request finished: %v", err)
+	default:
+	}
+
+	close(release)
+	assert.Equal(t, testResponse{body: "done"}, <-response)
+	assert.Nil(t, <-served)
+}
+
+func TestServerClosesRequestsExceedingShutdownTimeout(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	url, shutdown, served := startTestServer(t, blockingHandler(started, release), 50*time.Millisecond)
+
+	response := getAsync(url)
+	<-started
+	start := time.Now()
+	shutdown()
+
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Error(t, (<-response).err)
+}
+
+func TestServerReturnsListenerErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+
+	err = NewServer(http.NotFoundHandler(), DefaultServerSettings()).Serve(context.Background(), listener)
+
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
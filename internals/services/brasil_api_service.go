@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+const (
+	BrasilAPI_URL     = "https://brasilapi.com.br/api/cep/v2/%s"
+	BrasilAPI_Timeout = 5 * time.Second
+)
+
+// BrasilAPIService is a service to interact with the BrasilAPI CEP API
+type BrasilAPIService struct {
+	URL string
+	BaseHttpService
+}
+
+type BrasilAPIResponse struct {
+	Cep          string `json:"cep"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	Street       string `json:"street"`
+	Service      string `json:"service"`
+	Location     struct {
+		Coordinates struct {
+			Longitude string `json:"longitude"`
+			Latitude  string `json:"latitude"`
+		} `json:"coordinates"`
+	} `json:"location"`
+}
+
+// NewBrasilAPIService creates a new BrasilAPIService
+func NewBrasilAPIService(client internals.HTTPClient) CEPService {
+	return &BrasilAPIService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: BrasilAPI_Timeout},
+	}
+}
+
+// GetAddressByCEP returns the address for a given CEP
+func (b *BrasilAPIService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	var response BrasilAPIResponse
+	err := b.getCEPJSON(ctx, ProviderBrasilAPI, urlFor(b.URL, BrasilAPI_URL, cep.Digits()), &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.ToAddress(), nil
+}
+
+// ToAddress converts the BrasilAPI response into an Address
+func (r *BrasilAPIResponse) ToAddress() *Address {
+	return &Address{
+		Cep:          r.Cep,
+		Street:       r.Street,
+		Neighborhood: r.Neighborhood,
+		City:         r.City,
+		State:        r.State,
+		Coordinates:  r.coordinates(),
+		Provider:     ProviderBrasilAPI,
+	}
+}
+
+// coordinates parses the response location, which is often left empty
+func (r *BrasilAPIResponse) coordinates() *Coordinates {
+	lat, err := strconv.ParseFloat(r.Location.Coordinates.Latitude, 64)
+	if err != nil {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(r.Location.Coordinates.Longitude, 64)
+	if err != nil {
+		return nil
+	}
+	return &Coordinates{Lat: lat, Lon: lon}
+}
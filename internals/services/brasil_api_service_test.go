@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/stretchr/testify/assert"
+)
+
+const brasilAPIBody = `{
+	"cep": "01001000",
+	"state": "SP",
+	"city": "São Paulo",
+	"neighborhood": "Sé",
+	"street": "Praça da Sé",
+	"service": "open-cep",
+	"location": {
+		"type": "Point",
+		"coordinates": {
+			"longitude": "-46.6339",
+			"latitude": "-23.5507"
+		}
+	}
+}`
+
+// newCEPTestServer serves body for "/01001000" and the shared error cases
+// used by the CEP provider tests
+func newCEPTestServer(t *testing.T, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/01001000":
+			w.Write([]byte(body))
+		case "/NotFound":
+			w.WriteHeader(http.StatusNotFound)
+		case "/Unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/Invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/Malformed":
+			w.Write([]byte(`{"cep": `))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newBrasilAPITestService(t *testing.T) *BrasilAPIService {
+	server := newCEPTestServer(t, brasilAPIBody)
+	return &BrasilAPIService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+}
+
+func TestBrasilAPIGetAddressByCEP(t *testing.T) {
+	service := newBrasilAPITestService(t)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("01001000", response.Cep)
+	assert.Equal("Praça da Sé", response.Street)
+	assert.Equal("Sé", response.Neighborhood)
+	assert.Equal("São Paulo", response.City)
+	assert.Equal("SP", response.State)
+	assert.Equal(&Coordinates{Lat: -23.5507, Lon: -46.6339}, response.Coordinates)
+	assert.Equal(ProviderBrasilAPI, response.Provider)
+}
+
+func TestBrasilAPIResponseWithoutCoordinates(t *testing.T) {
+	response := BrasilAPIResponse{Cep: "01001000"}
+	assert.Nil(t, response.ToAddress().Coordinates)
+}
+
+func TestBrasilAPIGetAddressErrors(t *testing.T) {
+	service := newBrasilAPITestService(t)
+
+	tests := []struct {
+		cep      string
+		expected error
+	}{
+		{"NotFound", ErrCEPNotFound},
+		{"Unavailable", ErrCEPServiceUnavailable},
+		{"Invalid", ErrInvalidCEP},
+	}
+	for _, test := range tests {
+		t.Run(test.cep, func(t *testing.T) {
+			response, err := service.GetAddressByCEP(context.Background(), cep.CEP(test.cep))
+			assert.Nil(t, response)
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
+
+func TestBrasilAPIGetAddressMalformed(t *testing.T) {
+	service := newBrasilAPITestService(t)
+
+	response, err := service.GetAddressByCEP(context.Background(), "Malformed")
+
+	assert := assert.New(t)
+	assert.Nil(response)
+	assert.ErrorIs(err, ErrMalformedResponse)
+	assert.EqualError(err, "brasilapi: malformed upstream response: unexpected end of JSON input")
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cache"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+const (
+	CEPCache_TTL         = 24 * time.Hour
+	CEPCache_NotFoundTTL = time.Hour
+)
+
+// CachedCEPService caches the addresses returned by a CEPService. CEPs
+// that are not found are cached as well, for NotFoundTTL.
+type CachedCEPService struct {
+	Service     CEPService
+	Store       cache.Store
+	TTL         time.Duration
+	NotFoundTTL time.Duration
+}
+
+type cepCacheEntry struct {
+	Address  *Address `json:"address,omitempty"`
+	NotFound bool     `json:"not_found,omitempty"`
+}
+
+// NewCachedCEPService creates a new CachedCEPService
+func NewCachedCEPService(service CEPService, store cache.Store, ttl, notFoundTTL time.Duration) CEPService {
+	return &CachedCEPService{
+		Service:     service,
+		Store:       store,
+		TTL:         ttl,
+		NotFoundTTL: notFoundTTL,
+	}
+}
+
+// GetAddressByCEP returns the cached address for a CEP, querying the
+// wrapped service on a miss
+func (c *CachedCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	key := "cep:" + cep.Digits()
+
+	var entry cepCacheEntry
+	if getCached(ctx, c.Store, key, &entry) {
+		if entry.NotFound {
+			return nil, ErrCEPNotFound
+		}
+		return entry.Address, nil
+	}
+
+	address, err := c.Service.GetAddressByCEP(ctx, cep)
+	if errors.Is(err, ErrCEPNotFound) {
+		setCached(ctx, c.Store, key, cepCacheEntry{NotFound: true}, c.NotFoundTTL)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	setCached(ctx, c.Store, key, cepCacheEntry{Address: address}, c.TTL)
+	return address, nil
+}
+
+// getCached decodes the value stored for key into out, reporting whether it
+// was found. Store failures are logged and handled as misses.
+func getCached(ctx context.Context, store cache.Store, key string, out any) bool {
+	value, ok, err := store.Get(ctx, key)
+	if err != nil {
+		log.Printf("error reading cache key %s: %v\n", key, err)
+		return false
+	} else if !ok {
+		return false
+	}
+	err = json.Unmarshal(value, out)
+	if err != nil {
+		log.Printf("error decoding cache key %s: %v\n", key, err)
+		return false
+	}
+	return true
+}
+
+// setCached encodes value and stores it for key during ttl. Store failures
+// are only logged, as the value can be fetched again.
+func setCached(ctx context.Context, store cache.Store, key string, value any, ttl time.Duration) {
+	encoded, err := json.Marshal(value)
+	if err == nil {
+		err = store.Set(ctx, key, encoded, ttl)
+	}
+	if err != nil {
+		log.Printf("error writing cache key %s: %v\n", key, err)
+	}
+}
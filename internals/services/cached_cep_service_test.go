@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cache"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingCEPService answers from addresses, counting the calls received
+type countingCEPService struct {
+	addresses map[string]*Address
+	err       error
+	calls     int32
+}
+
+func (c *countingCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	address, ok := c.addresses[cep.Digits()]
+	if !ok {
+		return nil, ErrCEPNotFound
+	}
+	clone := *address
+	return &clone, nil
+}
+
+func TestCachedCEPServiceHit(t *testing.T) {
+	upstream := &countingCEPService{addresses: map[string]*Address{
+		"01001000": {Cep: "01001-000", City: "São Paulo", State: "SP"},
+	}}
+	service := NewCachedCEPService(upstream, cache.NewMemory(10), time.Hour, time.Minute)
+
+	first, err := service.GetAddressByCEP(context.Background(), "01001000")
+	assert.Nil(t, err)
+	first.City = "changed by caller"
+	formatted, _ := cep.Parse("01001-000")
+	second, err := service.GetAddressByCEP(context.Background(), formatted)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("São Paulo", second.City)
+	assert.Equal(int32(1), upstream.calls)
+}
+
+func TestCachedCEPServiceNegativeCaching(t *testing.T) {
+	upstream := &countingCEPService{}
+	service := NewCachedCEPService(upstream, cache.NewMemory(10), time.Hour, time.Minute)
+
+	_, err := service.GetAddressByCEP(context.Background(), "99999999")
+	assert.Equal(t, ErrCEPNotFound, err)
+	_, err = service.GetAddressByCEP(context.Background(), "99999999")
+
+	assert.Equal(t, ErrCEPNotFound, err)
+	assert.Equal(t, int32(1), upstream.calls)
+}
+
+func TestCachedCEPServiceDoesNotCacheErrors(t *testing.T) {
+	upstream := &countingCEPService{err: errors.New("upstream down")}
+	service := NewCachedCEPService(upstream, cache.NewMemory(10), time.Hour, time.Minute)
+
+	service.GetAddressByCEP(context.Background(), "01001000")
+	_, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert.EqualError(t, err, "upstream down")
+	assert.Equal(t, int32(2), upstream.calls)
+}
+
+func TestCachedCEPServiceExpiration(t *testing.T) {
+	upstream := &countingCEPService{addresses: map[string]*Address{
+		"01001000": {Cep: "01001-000", City: "São Paulo", State: "SP"},
+	}}
+	service := NewCachedCEPService(upstream, cache.NewMemory(10), time.Millisecond, time.Millisecond)
+
+	service.GetAddressByCEP(context.Background(), "01001000")
+	time.Sleep(5 * time.Millisecond)
+	service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert.Equal(t, int32(2), upstream.calls)
+}
+
+// brokenStore is a cache.Store whose every call fails
+type brokenStore struct{}
+
+func (brokenStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (brokenStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestCachedCEPServiceStoreFailure(t *testing.T) {
+	upstream := &countingCEPService{addresses: map[string]*Address{
+		"01001000": {Cep: "01001-000", City: "São Paulo", State: "SP"},
+	}}
+	service := NewCachedCEPService(upstream, brokenStore{}, time.Hour, time.Minute)
+
+	address, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "São Paulo", address.City)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cache"
+)
+
+const WeatherCache_TTL = 15 * time.Minute
+
+// CachedWeatherService caches the weather returned by a WeatherService,
+// keyed by the queried location
+type CachedWeatherService struct {
+	Service WeatherService
+	Store   cache.Store
+	TTL     time.Duration
+}
+
+// NewCachedWeatherService creates a new CachedWeatherService
+func NewCachedWeatherService(service WeatherService, store cache.Store, ttl time.Duration) WeatherService {
+	return &CachedWeatherService{
+		Service: service,
+		Store:   store,
+		TTL:     ttl,
+	}
+}
+
+// GetWeatherByCity returns the cached weather for a city, querying the
+// wrapped service on a miss
+func (c *CachedWeatherService) GetWeatherByCity(ctx context.Context, city string) (*Weather, error) {
+	return c.cached(ctx, cityCacheKey(city), func() (*Weather, error) {
+		return c.Service.GetWeatherByCity(ctx, city)
+	})
+}
+
+// GetWeatherByCoordinates returns the cached weather for given coordinates,
+// querying the wrapped service on a miss
+func (c *CachedWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error) {
+	return c.cached(ctx, coordinatesCacheKey(lat, lon), func() (*Weather, error) {
+		return c.Service.GetWeatherByCoordinates(ctx, lat, lon)
+	})
+}
+
+func (c *CachedWeatherService) cached(ctx context.Context, key string, fetch func() (*Weather, error)) (*Weather, error) {
+	var weather Weather
+	if getCached(ctx, c.Store, key, &weather) {
+		return &weather, nil
+	}
+	fetched, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	setCached(ctx, c.Store, key, fetched, c.TTL)
+	return fetched, nil
+}
+
+func cityCacheKey(city string) string {
+	return "weather:city:" + strings.ToLower(strings.TrimSpace(city))
+}
+
+// coordinatesCacheKey rounds coordinates to about 1km, as weather does not
+// change within that distance
+func coordinatesCacheKey(lat, lon float64) string {
+	return fmt.Sprintf("weather:coords:%.2f,%.2f", lat, lon)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingWeatherService answers with weather, counting the calls received
+type countingWeatherService struct {
+	weather *Weather
+	err     error
+	calls   int32
+}
+
+func (c *countingWeatherService) GetWeatherByCity(ctx context.Context, city string) (*Weather, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	weather := *c.weather
+	weather.Location.Name = city
+	return &weather, nil
+}
+
+func (c *countingWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	weather := *c.weather
+	weather.Location.Lat, weather.Location.Lon = lat, lon
+	return &weather, nil
+}
+
+func TestCachedWeatherServiceByCity(t *testing.T) {
+	upstream := &countingWeatherService{weather: &Weather{Current: CurrentWeather{TempC: 21}}}
+	service := NewCachedWeatherService(upstream, cache.NewMemory(10), time.Minute)
+
+	service.GetWeatherByCity(context.Background(), "São Paulo, São Paulo, Brazil")
+	weather, err := service.GetWeatherByCity(context.Background(), "são paulo, são paulo, brazil ")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(float64(21), weather.Current.TempC)
+	assert.Equal(int32(1), upstream.calls)
+
+	service.GetWeatherByCity(context.Background(), "Campinas, São Paulo, Brazil")
+	assert.Equal(int32(2), upstream.calls)
+}
+
+func TestCachedWeatherServiceByCoordinates(t *testing.T) {
+	upstream := &countingWeatherService{weather: &Weather{Current: CurrentWeather{TempC: 21}}}
+	service := NewCachedWeatherService(upstream, cache.NewMemory(10), time.Minute)
+
+	service.GetWeatherByCoordinates(context.Background(), -23.5329, -46.6395)
+	service.GetWeatherByCoordinates(context.Background(), -23.5331, -46.6393)
+	service.GetWeatherByCoordinates(context.Background(), -22.9053, -47.0659)
+
+	assert.Equal(t, int32(2), upstream.calls)
+}
+
+func TestCachedWeatherServiceDoesNotCacheErrors(t *testing.T) {
+	upstream := &countingWeatherService{err: errors.New("upstream down")}
+	service := NewCachedWeatherService(upstream, cache.NewMemory(10), time.Minute)
+
+	service.GetWeatherByCity(context.Background(), "São Paulo")
+	_, err := service.GetWeatherByCity(context.Background(), "São Paulo")
+
+	assert.EqualError(t, err, "upstream down")
+	assert.Equal(t, int32(2), upstream.calls)
+}
+
+func TestCachedWeatherServiceEvictsLeastRecentlyUsed(t *testing.T) {
+	upstream := &countingWeatherService{weather: &Weather{}}
+	service := NewCachedWeatherService(upstream, cache.NewMemory(2), time.Minute)
+
+	service.GetWeatherByCity(context.Background(), "a")
+	service.GetWeatherByCity(context.Background(), "b")
+	service.GetWeatherByCity(context.Background(), "c")
+	service.GetWeatherByCity(context.Background(), "a")
+
+	assert.Equal(t, int32(4), upstream.calls)
+}
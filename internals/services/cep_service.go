@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+const (
+	ProviderViaCEP    = "viacep"
+	ProviderBrasilAPI = "brasilapi"
+	ProviderOpenCEP   = "opencep"
+	ProviderPostmon   = "postmon"
+)
+
+const (
+	CEPStrategyFallback = "fallback"
+	CEPStrategyRace     = "race"
+)
+
+// DefaultCEPProviders is the provider order used when none is configured
+var DefaultCEPProviders = []string{ProviderViaCEP, ProviderBrasilAPI, ProviderOpenCEP, ProviderPostmon}
+
+type CEPService interface {
+	GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error)
+}
+
+// CEPSearchService is implemented by the CEPService providers able to find
+// the CEPs of an address
+type CEPSearchService interface {
+	SearchCEP(ctx context.Context, query AddressQuery) ([]Address, error)
+}
+
+// AddressQuery is a partial address whose CEPs are searched
+type AddressQuery struct {
+	UF     string
+	City   string
+	Street string
+}
+
+// Address is the provider-neutral address returned by every CEPService
+type Address struct {
+	Cep          string       `json:"cep"`
+	Street       string       `json:"street"`
+	Complement   string       `json:"complement"`
+	Neighborhood string       `json:"neighborhood"`
+	City         string       `json:"city"`
+	State        string       `json:"state"`
+	Ibge         string       `json:"ibge"`
+	Ddd          string       `json:"ddd"`
+	Coordinates  *Coordinates `json:"coordinates,omitempty"`
+	Provider     string       `json:"provider"`
+}
+
+// Coordinates is a geographic position in decimal degrees
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// StateNames maps each UF to its state name
+var StateNames = map[string]string{
+	"AC": "Acre",
+	"AL": "Alagoas",
+	"AP": "Amapá",
+	"AM": "Amazonas",
+	"BA": "Bahia",
+	"CE": "Ceará",
+	"DF": "Distrito Federal",
+	"ES": "Espírito Santo",
+	"GO": "Goiás",
+	"MA": "Maranhão",
+	"MT": "Mato Grosso",
+	"MS": "Mato Grosso do Sul",
+	"MG": "Minas Gerais",
+	"PA": "Pará",
+	"PB": "Paraíba",
+	"PR": "Paraná",
+	"PE": "Pernambuco",
+	"PI": "Piauí",
+	"RJ": "Rio de Janeiro",
+	"RN": "Rio Grande do Norte",
+	"RS": "Rio Grande do Sul",
+	"RO": "Rondônia",
+	"RR": "Roraima",
+	"SC": "Santa Catarina",
+	"SP": "São Paulo",
+	"SE": "Sergipe",
+	"TO": "Tocantins",
+}
+
+// WeatherQuery returns a "city, state, country" query for the address, so
+// homonymous cities in other states or countries are not picked instead
+func (a *Address) WeatherQuery() string {
+	parts := []string{a.City}
+	if state, ok := StateNames[strings.ToUpper(a.State)]; ok {
+		parts = append(parts, state)
+	} else if a.State != "" {
+		parts = append(parts, a.State)
+	}
+	return strings.Join(append(parts, "Brazil"), ", ")
+}
+
+// CEPProvider is a CEPService identified by its provider name
+type CEPProvider struct {
+	Name    string
+	Service CEPService
+}
+
+// NewCEPProvider creates the CEPProvider registered under name, reaching
+// it through the client created by newClient
+func NewCEPProvider(name string, newClient internals.ClientFactory) (CEPProvider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var service CEPService
+	switch name {
+	case ProviderViaCEP:
+		service = NewViaCEPService(newClient(name))
+	case ProviderBrasilAPI:
+		service = NewBrasilAPIService(newClient(name))
+	case ProviderOpenCEP:
+		service = NewOpenCEPService(newClient(name))
+	case ProviderPostmon:
+		service = NewPostmonService(newClient(name))
+	default:
+		return CEPProvider{}, fmt.Errorf("unknown CEP provider: %q", name)
+	}
+	return CEPProvider{Name: name, Service: service}, nil
+}
+
+// NewCEPProviders creates the CEPProviders for names, keeping their order
+func NewCEPProviders(names []string, newClient internals.ClientFactory) ([]CEPProvider, error) {
+	if len(names) == 0 {
+		names = DefaultCEPProviders
+	}
+	providers := make([]CEPProvider, 0, len(names))
+	for _, name := range names {
+		provider, err := NewCEPProvider(name, newClient)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// NewCEPSearchService combines the providers able to search CEPs, falling
+// back between them as FallbackCEPService does, or returns nil when none is
+func NewCEPSearchService(providers []CEPProvider) CEPSearchService {
+	var searchers []CEPProvider
+	for _, provider := range providers {
+		if _, ok := provider.Service.(CEPSearchService); ok {
+			searchers = append(searchers, provider)
+		}
+	}
+	if len(searchers) == 0 {
+		return nil
+	}
+	return &FallbackCEPService{Providers: searchers}
+}
+
+// NewCEPServiceWithStrategy combines providers using the named strategy
+func NewCEPServiceWithStrategy(strategy string, providers []CEPProvider) (CEPService, error) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", CEPStrategyFallback:
+		return NewFallbackCEPService(providers...), nil
+	case CEPStrategyRace:
+		return NewRacingCEPService(providers...), nil
+	default:
+		return nil, fmt.Errorf("unknown CEP strategy: %q", strategy)
+	}
+}
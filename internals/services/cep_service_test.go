@@ -0,0 +1,14 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressWeatherQuery(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("Santa Cruz, Rio Grande do Norte, Brazil", (&Address{City: "Santa Cruz", State: "RN"}).WeatherQuery())
+	assert.Equal("Santa Cruz, XX, Brazil", (&Address{City: "Santa Cruz", State: "XX"}).WeatherQuery())
+	assert.Equal("Santa Cruz, Brazil", (&Address{City: "Santa Cruz"}).WeatherQuery())
+}
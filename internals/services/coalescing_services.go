@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+// CoalescingCEPService deduplicates concurrent lookups of the same CEP, so
+// a burst of identical requests makes a single upstream call
+type CoalescingCEPService struct {
+	Service CEPService
+	group   singleflight.Group
+}
+
+// NewCoalescingCEPService creates a new CoalescingCEPService
+func NewCoalescingCEPService(service CEPService) CEPService {
+	return &CoalescingCEPService{Service: service}
+}
+
+// GetAddressByCEP returns the address for a CEP, sharing the upstream call
+// with concurrent lookups of the same CEP
+func (c *CoalescingCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	value, err := coalesce(ctx, &c.group, "cep:"+cep.Digits(), func(ctx context.Context) (any, error) {
+		return c.Service.GetAddressByCEP(ctx, cep)
+	})
+	if err != nil {
+		return nil, err
+	}
+	address := *value.(*Address)
+	if address.Coordinates != nil {
+		coordinates := *address.Coordinates
+		address.Coordinates = &coordinates
+	}
+	return &address, nil
+}
+
+// CoalescingWeatherService deduplicates concurrent lookups of the same
+// location, so a burst of identical requests makes a single upstream call
+type CoalescingWeatherService struct {
+	Service WeatherService
+	group   singleflight.Group
+}
+
+// NewCoalescingWeatherService creates a new CoalescingWeatherService
+func NewCoalescingWeatherService(service WeatherService) WeatherService {
+	return &CoalescingWeatherService{Service: service}
+}
+
+// GetWeatherByCity returns the weather for a city, sharing the upstream
+// call with concurrent lookups of the same city
+func (c *CoalescingWeatherService) GetWeatherByCity(ctx context.Context, city string) (*Weather, error) {
+	return c.weather(ctx, cityCacheKey(city), func(ctx context.Context) (any, error) {
+		return c.Service.GetWeatherByCity(ctx, city)
+	})
+}
+
+// GetWeatherByCoordinates returns the weather for given coordinates, sharing
+// the upstream call with concurrent lookups of the same coordinates
+func (c *CoalescingWeatherService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error) {
+	key := "weather:coords:" + formatCoordinates(lat, lon)
+	return c.weather(ctx, key, func(ctx context.Context) (any, error) {
+		return c.Service.GetWeatherByCoordinates(ctx, lat, lon)
+	})
+}
+
+func (c *CoalescingWeatherService) weather(ctx context.Context, key string, fetch func(context.Context) (any, error)) (*Weather, error) {
+	value, err := coalesce(ctx, &c.group, key, fetch)
+	if err != nil {
+		return nil, err
+	}
+	weather := *value.(*Weather)
+	return &weather, nil
+}
+
+// coalesce runs fetch once for all concurrent callers of key. The shared
+// call is detached from the cancellation of the caller that started it, so
+// the others are not failed by it, while each caller still stops waiting
+// when its own context is done.
+func coalesce(ctx context.Context, group *singleflight.Group, key string, fetch func(context.Context) (any, error)) (any, error) {
+	shared := context.WithoutCancel(ctx)
+	result := group.DoChan(key, func() (any, error) {
+		return fetch(shared)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		return res.Val, res.Err
+	}
+}
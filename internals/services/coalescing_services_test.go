@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newGatedTestServer answers body once release is closed, counting the
+// requests received
+func newGatedTestServer(t *testing.T, body string, release <-chan struct{}, hits *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		<-release
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runConcurrently calls fn from n goroutines, releasing the upstream once
+// they are all waiting on it
+func runConcurrently(n int, release chan<- struct{}, fn func()) {
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			fn()
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+}
+
+func TestCoalescingCEPService(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := newGatedTestServer(t, CEPBody, release, &hits)
+	service := NewCoalescingCEPService(&ViaCEPService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	})
+
+	var mu sync.Mutex
+	var addresses []*Address
+	runConcurrently(20, release, func() {
+		address, err := service.GetAddressByCEP(context.Background(), "01001000")
+		assert.Nil(t, err)
+		mu.Lock()
+		addresses = append(addresses, address)
+		mu.Unlock()
+	})
+
+	assert := assert.New(t)
+	assert.Equal(int32(1), atomic.LoadInt32(&hits))
+	assert.Len(addresses, 20)
+	for _, address := range addresses {
+		assert.Equal("São Paulo", address.City)
+	}
+	assert.NotSame(addresses[0], addresses[1])
+}
+
+func TestCoalescingWeatherService(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := newGatedTestServer(t, weatherAPIBody, release, &hits)
+	service := NewCoalescingWeatherService(&WeatherAPIService{
+		URL:             server.URL,
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	})
+
+	runConcurrently(20, release, func() {
+		weather, err := service.GetWeatherByCoordinates(context.Background(), -27.58, -48.57)
+		assert.Nil(t, err)
+		assert.Equal(t, float64(21), weather.Current.TempC)
+	})
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestCoalescingWeatherServiceDistinctKeys(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	close(release)
+	server := newGatedTestServer(t, weatherAPIBody, release, &hits)
+	service := NewCoalescingWeatherService(&WeatherAPIService{
+		URL:             server.URL,
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	})
+
+	service.GetWeatherByCity(context.Background(), "Florianópolis")
+	service.GetWeatherByCity(context.Background(), "São Paulo")
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestCoalescingCallerCancellation(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := newGatedTestServer(t, CEPBody, release, &hits)
+	service := NewCoalescingCEPService(&ViaCEPService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := service.GetAddressByCEP(ctx, "01001000")
+		first <- err
+	}()
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error, 1)
+	go func() {
+		_, err := service.GetAddressByCEP(context.Background(), "01001000")
+		second <- err
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	assert.Nil(t, <-second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+// FallbackCEPService tries its providers in order until one answers.
+// Transport errors, 5xx and malformed responses move on to the next
+// provider, while invalid or not found CEPs are final answers.
+type FallbackCEPService struct {
+	Providers []CEPProvider
+}
+
+// NewFallbackCEPService creates a new FallbackCEPService
+func NewFallbackCEPService(providers ...CEPProvider) CEPService {
+	return &FallbackCEPService{Providers: providers}
+}
+
+// GetAddressByCEP returns the address from the first provider that answers
+func (f *FallbackCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	var lastErr error
+	for _, provider := range f.Providers {
+		address, err := provider.Service.GetAddressByCEP(ctx, cep)
+		if err == nil {
+			return address, nil
+		}
+		if isFinalCEPError(err) {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("CEP provider %s failed, trying next: %v\n", provider.Name, err)
+		lastErr = err
+	}
+	return nil, fallbackError(lastErr)
+}
+
+// SearchCEP returns the addresses found by the first provider able to search
+// CEPs that answers, following the same rules as GetAddressByCEP
+func (f *FallbackCEPService) SearchCEP(ctx context.Context, query AddressQuery) ([]Address, error) {
+	var lastErr error
+	for _, provider := range f.Providers {
+		searcher, ok := provider.Service.(CEPSearchService)
+		if !ok {
+			continue
+		}
+		addresses, err := searcher.SearchCEP(ctx, query)
+		if err == nil {
+			return addresses, nil
+		}
+		if isFinalCEPError(err) {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("CEP provider %s failed to search, trying next: %v\n", provider.Name, err)
+		lastErr = err
+	}
+	return nil, fallbackError(lastErr)
+}
+
+// fallbackError returns the error reported once every provider failed with
+// lastErr being the error of the last one
+func fallbackError(lastErr error) error {
+	if lastErr == nil {
+		return ErrCEPServiceUnavailable
+	} else if errors.Is(lastErr, ErrCEPServiceUnavailable) {
+		return lastErr
+	}
+	return fmt.Errorf("%w: %w", ErrCEPServiceUnavailable, lastErr)
+}
+
+// isFinalCEPError reports whether err is a definitive answer about the CEP
+func isFinalCEPError(err error) bool {
+	return errors.Is(err, ErrCEPNotFound) || errors.Is(err, ErrInvalidCEP)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFallbackTestServer answers every request with status and body,
+// counting how many requests it received
+func newFallbackTestServer(t *testing.T, status int, body string, hits *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFallbackCEPServiceFallsThroughOn5xx(t *testing.T) {
+	var viaCEPHits, brasilAPIHits, openCEPHits int32
+	viaCEP := newFallbackTestServer(t, http.StatusBadGateway, ``, &viaCEPHits)
+	brasilAPI := newFallbackTestServer(t, http.StatusOK, brasilAPIBody, &brasilAPIHits)
+	openCEP := newFallbackTestServer(t, http.StatusOK, openCEPBody, &openCEPHits)
+
+	service := NewFallbackCEPService(
+		CEPProvider{ProviderViaCEP, &ViaCEPService{URL: viaCEP.URL + "/%s", BaseHttpService: BaseHttpService{Client: viaCEP.Client()}}},
+		CEPProvider{ProviderBrasilAPI, &BrasilAPIService{URL: brasilAPI.URL + "/%s", BaseHttpService: BaseHttpService{Client: brasilAPI.Client()}}},
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: openCEP.URL + "/%s", BaseHttpService: BaseHttpService{Client: openCEP.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(ProviderBrasilAPI, response.Provider)
+	assert.Equal("São Paulo", response.City)
+	assert.Equal(int32(1), viaCEPHits)
+	assert.Equal(int32(1), brasilAPIHits)
+	assert.Equal(int32(0), openCEPHits)
+}
+
+func TestFallbackCEPServiceFallsThroughOnTransportError(t *testing.T) {
+	var postmonHits int32
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	postmon := newFallbackTestServer(t, http.StatusOK, postmonBody, &postmonHits)
+
+	service := NewFallbackCEPService(
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: down.URL + "/%s", BaseHttpService: BaseHttpService{Client: &http.Client{}}}},
+		CEPProvider{ProviderPostmon, &PostmonService{URL: postmon.URL + "/%s", BaseHttpService: BaseHttpService{Client: postmon.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(ProviderPostmon, response.Provider)
+	assert.Equal(int32(1), postmonHits)
+}
+
+func TestFallbackCEPServiceNotFoundIsFinal(t *testing.T) {
+	var viaCEPHits, brasilAPIHits int32
+	viaCEP := newFallbackTestServer(t, http.StatusOK, `{"erro": "true"}`, &viaCEPHits)
+	brasilAPI := newFallbackTestServer(t, http.StatusOK, brasilAPIBody, &brasilAPIHits)
+
+	service := NewFallbackCEPService(
+		CEPProvider{ProviderViaCEP, &ViaCEPService{URL: viaCEP.URL + "/%s", BaseHttpService: BaseHttpService{Client: viaCEP.Client()}}},
+		CEPProvider{ProviderBrasilAPI, &BrasilAPIService{URL: brasilAPI.URL + "/%s", BaseHttpService: BaseHttpService{Client: brasilAPI.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "99999999")
+
+	assert := assert.New(t)
+	assert.Nil(response)
+	assert.Equal(ErrCEPNotFound, err)
+	assert.Equal(int32(1), viaCEPHits)
+	assert.Equal(int32(0), brasilAPIHits)
+}
+
+func TestFallbackCEPServiceAllProvidersDown(t *testing.T) {
+	var viaCEPHits, postmonHits int32
+	viaCEP := newFallbackTestServer(t, http.StatusInternalServerError, ``, &viaCEPHits)
+	postmon := newFallbackTestServer(t, http.StatusServiceUnavailable, ``, &postmonHits)
+
+	service := NewFallbackCEPService(
+		CEPProvider{ProviderViaCEP, &ViaCEPService{URL: viaCEP.URL + "/%s", BaseHttpService: BaseHttpService{Client: viaCEP.Client()}}},
+		CEPProvider{ProviderPostmon, &PostmonService{URL: postmon.URL + "/%s", BaseHttpService: BaseHttpService{Client: postmon.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(response)
+	assert.ErrorIs(err, ErrCEPServiceUnavailable)
+	assert.Equal(int32(1), viaCEPHits)
+	assert.Equal(int32(1), postmonHits)
+}
+
+func TestNewCEPProviders(t *testing.T) {
+	providers, err := NewCEPProviders([]string{"BrasilAPI", " viacep "}, internals.DefaultClient)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Len(providers, 2)
+	assert.Equal(ProviderBrasilAPI, providers[0].Name)
+	assert.Equal(ProviderViaCEP, providers[1].Name)
+
+	_, err = NewCEPProviders([]string{"viacep", "correios"}, internals.DefaultClient)
+	assert.EqualError(err, `unknown CEP provider: "correios"`)
+}
+
+func TestFallbackCEPServiceSearchCEP(t *testing.T) {
+	var downHits, viaCEPHits int32
+	down := newFallbackTestServer(t, http.StatusServiceUnavailable, ``, &downHits)
+	viaCEP := newFallbackTestServer(t, http.StatusOK, `[`+CEPBody+`]`, &viaCEPHits)
+
+	service := NewCEPSearchService([]CEPProvider{
+		{ProviderBrasilAPI, &BrasilAPIService{}},
+		{ProviderViaCEP, &ViaCEPService{SearchURL: down.URL + "/%s/%s/%s", BaseHttpService: BaseHttpService{Client: down.Client()}}},
+		{ProviderViaCEP, &ViaCEPService{SearchURL: viaCEP.URL + "/%s/%s/%s", BaseHttpService: BaseHttpService{Client: viaCEP.Client()}}},
+	})
+
+	addresses, err := service.SearchCEP(context.Background(), AddressQuery{UF: "SP", City: "São Paulo", Street: "Praça da Sé"})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Len(addresses, 1)
+	assert.Equal(int32(1), downHits)
+	assert.Equal(int32(1), viaCEPHits)
+}
+
+func TestFallbackCEPServiceSearchCEPNotFoundIsFinal(t *testing.T) {
+	var emptyHits, viaCEPHits int32
+	empty := newFallbackTestServer(t, http.StatusOK, `[]`, &emptyHits)
+	viaCEP := newFallbackTestServer(t, http.StatusOK, `[`+CEPBody+`]`, &viaCEPHits)
+
+	service := NewCEPSearchService([]CEPProvider{
+		{ProviderViaCEP, &ViaCEPService{SearchURL: empty.URL + "/%s/%s/%s", BaseHttpService: BaseHttpService{Client: empty.Client()}}},
+		{ProviderViaCEP, &ViaCEPService{SearchURL: viaCEP.URL + "/%s/%s/%s", BaseHttpService: BaseHttpService{Client: viaCEP.Client()}}},
+	})
+
+	_, err := service.SearchCEP(context.Background(), AddressQuery{UF: "SP", City: "São Paulo", Street: "Nowhere"})
+
+	assert.ErrorIs(t, err, ErrCEPNotFound)
+	assert.Equal(t, int32(0), viaCEPHits)
+}
+
+func TestNewCEPSearchServiceWithoutSearchProviders(t *testing.T) {
+	service := NewCEPSearchService([]CEPProvider{{ProviderBrasilAPI, &BrasilAPIService{}}, {ProviderPostmon, &PostmonService{}}})
+
+	assert.Nil(t, service)
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	Forecast_DefaultDays = 3
+	Forecast_MaxDays     = 14
+)
+
+// ForecastService is implemented by the WeatherService providers able to
+// forecast the weather
+type ForecastService interface {
+	GetForecastByCity(ctx context.Context, city string, days int) (*Forecast, error)
+	GetForecastByCoordinates(ctx context.Context, lat, lon float64, days int) (*Forecast, error)
+}
+
+// Forecast is the provider-neutral daily and hourly forecast of a location
+type Forecast struct {
+	Location WeatherLocation `json:"location"`
+	Days     []ForecastDay   `json:"days"`
+	Provider string          `json:"provider"`
+}
+
+// ForecastDay holds the forecast, or the observed weather, of a day, Date
+// being midnight in the location time zone
+type ForecastDay struct {
+	Date         time.Time      `json:"date"`
+	MinTempC     float64        `json:"mintemp_c"`
+	MinTempF     float64        `json:"mintemp_f"`
+	MaxTempC     float64        `json:"maxtemp_c"`
+	MaxTempF     float64        `json:"maxtemp_f"`
+	AvgTempC     float64        `json:"avgtemp_c"`
+	ChanceOfRain int            `json:"chance_of_rain"`
+	PrecipMm     float64        `json:"precip_mm"`
+	Condition    string         `json:"condition"`
+	Hours        []ForecastHour `json:"hours"`
+}
+
+// ForecastHour holds the forecast, or the observed weather, of an hour
+type ForecastHour struct {
+	Time         time.Time `json:"time"`
+	TempC        float64   `json:"temp_c"`
+	TempF        float64   `json:"temp_f"`
+	ChanceOfRain int       `json:"chance_of_rain"`
+	Condition    string    `json:"condition"`
+	IsDay        bool      `json:"is_day"`
+}
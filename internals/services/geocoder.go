@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/rcbadiale/go-cloud-run/internals/ibge"
+)
+
+// Geocoder resolves the coordinates of an address
+type Geocoder interface {
+	Geocode(ctx context.Context, address *Address) (*Coordinates, error)
+}
+
+// IBGEGeocoder resolves coordinates offline from the embedded IBGE
+// municipality table, by IBGE code or by city and state
+type IBGEGeocoder struct{}
+
+// NewIBGEGeocoder creates a new IBGEGeocoder
+func NewIBGEGeocoder() Geocoder {
+	return IBGEGeocoder{}
+}
+
+// Geocode returns the coordinates of the address municipality
+func (IBGEGeocoder) Geocode(ctx context.Context, address *Address) (*Coordinates, error) {
+	municipality, ok := ibge.Lookup(address.Ibge)
+	if !ok {
+		municipality, ok = ibge.FindByName(address.State, address.City)
+	}
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+	return &Coordinates{Lat: municipality.Lat, Lon: municipality.Lon}, nil
+}
+
+// FallbackGeocoder tries each Geocoder in order until one resolves the address
+type FallbackGeocoder []Geocoder
+
+// Geocode returns the coordinates from the first Geocoder that resolves them
+func (f FallbackGeocoder) Geocode(ctx context.Context, address *Address) (*Coordinates, error) {
+	err := ErrLocationNotFound
+	for _, geocoder := range f {
+		var coordinates *Coordinates
+		coordinates, err = geocoder.Geocode(ctx, address)
+		if err == nil {
+			return coordinates, nil
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("geocoder %T failed, trying next: %v\n", geocoder, err)
+	}
+	return nil, err
+}
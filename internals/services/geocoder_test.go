@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubGeocoder struct {
+	coordinates *Coordinates
+	err         error
+	calls       int
+}
+
+func (s *stubGeocoder) Geocode(ctx context.Context, address *Address) (*Coordinates, error) {
+	s.calls++
+	return s.coordinates, s.err
+}
+
+func TestIBGEGeocoderByCode(t *testing.T) {
+	coordinates, err := NewIBGEGeocoder().Geocode(context.Background(), &Address{Ibge: "3550308"})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(&Coordinates{Lat: -23.5329, Lon: -46.6395}, coordinates)
+}
+
+func TestIBGEGeocoderByName(t *testing.T) {
+	coordinates, err := NewIBGEGeocoder().Geocode(context.Background(), &Address{City: "Florianópolis", State: "SC"})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(&Coordinates{Lat: -27.5945, Lon: -48.5477}, coordinates)
+}
+
+func TestIBGEGeocoderNotFound(t *testing.T) {
+	_, err := NewIBGEGeocoder().Geocode(context.Background(), &Address{City: "Erroropolis", State: "SP"})
+
+	assert.Equal(t, ErrLocationNotFound, err)
+}
+
+func TestFallbackGeocoder(t *testing.T) {
+	failing := &stubGeocoder{err: errors.New("geocoder down")}
+	working := &stubGeocoder{coordinates: &Coordinates{Lat: 1, Lon: 2}}
+	unused := &stubGeocoder{coordinates: &Coordinates{Lat: 3, Lon: 4}}
+
+	coordinates, err := FallbackGeocoder{failing, working, unused}.Geocode(context.Background(), &Address{})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(&Coordinates{Lat: 1, Lon: 2}, coordinates)
+	assert.Equal(1, failing.calls)
+	assert.Equal(0, unused.calls)
+
+	_, err = FallbackGeocoder{failing}.Geocode(context.Background(), &Address{})
+	assert.EqualError(err, "geocoder down")
+
+	_, err = FallbackGeocoder{}.Geocode(context.Background(), &Address{})
+	assert.Equal(ErrLocationNotFound, err)
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// History_MaxDays is the longest range of days of a single history lookup
+const History_MaxDays = 30
+
+// History_MinDate is the first day with historical weather
+var History_MinDate = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// HistoryService is implemented by the WeatherService providers able to look
+// the past weather up, from and to being the first and last days included
+type HistoryService interface {
+	GetHistoryByCity(ctx context.Context, city string, from, to time.Time) (*History, error)
+	GetHistoryByCoordinates(ctx context.Context, lat, lon float64, from, to time.Time) (*History, error)
+}
+
+// History is the provider-neutral daily and hourly weather observed at a
+// location, using the same days as a Forecast
+type History struct {
+	Location WeatherLocation `json:"location"`
+	Days     []ForecastDay   `json:"days"`
+	Provider string          `json:"provider"`
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+)
+
+const (
+	ProviderIBGE         = "ibge"
+	IBGE_MunicipalityURL = "https://servicodados.ibge.gov.br/api/v1/localidades/municipios/%s"
+	IBGE_Timeout         = 5 * time.Second
+)
+
+// MunicipalityService resolves a municipality by its IBGE code
+type MunicipalityService interface {
+	// GetMunicipality returns the city and state of the municipality with
+	// the IBGE code, or ErrLocationNotFound when no municipality has it
+	GetMunicipality(ctx context.Context, code string) (*Address, error)
+}
+
+// IBGEService is a service to interact with the IBGE localidades API, which
+// lists every municipality but not its coordinates
+type IBGEService struct {
+	URL string
+	BaseHttpService
+}
+
+// IBGEMunicipalityResponse is a municipality of the IBGE localidades API.
+// Its state is found under both region hierarchies, either of which may be
+// null for recently created municipalities
+type IBGEMunicipalityResponse struct {
+	ID           int    `json:"id"`
+	Nome         string `json:"nome"`
+	Microrregiao *struct {
+		Mesorregiao struct {
+			UF IBGEState `json:"UF"`
+		} `json:"mesorregiao"`
+	} `json:"microrregiao"`
+	RegiaoImediata *struct {
+		RegiaoIntermediaria struct {
+			UF IBGEState `json:"UF"`
+		} `json:"regiao-intermediaria"`
+	} `json:"regiao-imediata"`
+}
+
+type IBGEState struct {
+	Sigla string `json:"sigla"`
+	Nome  string `json:"nome"`
+}
+
+// NewIBGEService creates a new IBGEService
+func NewIBGEService(client internals.HTTPClient) MunicipalityService {
+	return &IBGEService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: IBGE_Timeout},
+	}
+}
+
+// GetMunicipality returns the municipality with the IBGE code
+func (i *IBGEService) GetMunicipality(ctx context.Context, code string) (*Address, error) {
+	ctx, cancel := i.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := i.get(ctx, urlFor(i.URL, IBGE_MunicipalityURL, code))
+	if err != nil {
+		log.Println("error getting municipality: ", err)
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: ErrUpstreamUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("error reading response body: ", err)
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: ErrUpstreamUnavailable, Err: err}
+	} else if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrLocationNotFound
+	} else if kind := statusKind(resp.StatusCode, ErrUpstreamUnavailable); kind != nil {
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: kind, StatusCode: resp.StatusCode}
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: ErrMalformedResponse, StatusCode: resp.StatusCode}
+	}
+	// Unknown codes are answered with an empty list
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		return nil, ErrLocationNotFound
+	}
+
+	var response IBGEMunicipalityResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		log.Println("error on Unmarshal response body: ", err, string(body))
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: ErrMalformedResponse, Err: err}
+	}
+	address := response.ToAddress()
+	if address.City == "" || address.State == "" {
+		return nil, &UpstreamError{Service: ProviderIBGE, Kind: ErrMalformedResponse, Message: "municipality without name or state"}
+	}
+	return address, nil
+}
+
+// ToAddress converts the IBGE municipality into an Address
+func (r *IBGEMunicipalityResponse) ToAddress() *Address {
+	address := &Address{City: r.Nome, Provider: ProviderIBGE}
+	if r.ID != 0 {
+		address.Ibge = strconv.Itoa(r.ID)
+	}
+	switch {
+	case r.RegiaoImediata != nil && r.RegiaoImediata.RegiaoIntermediaria.UF.Sigla != "":
+		address.State = r.RegiaoImediata.RegiaoIntermediaria.UF.Sigla
+	case r.Microrregiao != nil:
+		address.State = r.Microrregiao.Mesorregiao.UF.Sigla
+	}
+	return address
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ibgeMunicipalityBody = `{
+	"id": 3304904,
+	"nome": "São Gonçalo",
+	"microrregiao": {
+		"id": 33018,
+		"nome": "Rio de Janeiro",
+		"mesorregiao": {
+			"id": 3306,
+			"nome": "Metropolitana do Rio de Janeiro",
+			"UF": {"id": 33, "sigla": "RJ", "nome": "Rio de Janeiro"}
+		}
+	},
+	"regiao-imediata": {
+		"id": 330001,
+		"nome": "Rio de Janeiro",
+		"regiao-intermediaria": {
+			"id": 3301,
+			"nome": "Rio de Janeiro",
+			"UF": {"id": 33, "sigla": "RJ", "nome": "Rio de Janeiro"}
+		}
+	}
+}`
+
+func newIBGETestService(t *testing.T) *IBGEService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/3304904":
+			w.Write([]byte(ibgeMunicipalityBody))
+		case "/5300050":
+			w.Write([]byte(`{"id": 5300050, "nome": "Nova Cidade", "microrregiao": null, "regiao-imediata": {"regiao-intermediaria": {"UF": {"sigla": "DF"}}}}`))
+		case "/1234567":
+			w.Write([]byte(`[]`))
+		case "/Unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/Invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/Malformed":
+			w.Write([]byte(`{"id": `))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &IBGEService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+}
+
+func TestIBGEGetMunicipality(t *testing.T) {
+	service := newIBGETestService(t)
+
+	address, err := service.GetMunicipality(context.Background(), "3304904")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(&Address{City: "São Gonçalo", State: "RJ", Ibge: "3304904", Provider: ProviderIBGE}, address)
+
+	address, err = service.GetMunicipality(context.Background(), "5300050")
+	assert.Nil(err)
+	assert.Equal("DF", address.State)
+}
+
+func TestIBGEGetMunicipalityErrors(t *testing.T) {
+	service := newIBGETestService(t)
+	for code, kind := range map[string]error{
+		"1234567":     ErrLocationNotFound,
+		"9999999":     ErrMalformedResponse,
+		"Unavailable": ErrUpstreamUnavailable,
+		"Invalid":     ErrMalformedResponse,
+		"Malformed":   ErrMalformedResponse,
+	} {
+		_, err := service.GetMunicipality(context.Background(), code)
+
+		assert.ErrorIs(t, err, kind, code)
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+const (
+	OpenCEP_URL     = "https://opencep.com/v1/%s"
+	OpenCEP_Timeout = 5 * time.Second
+)
+
+// OpenCEPService is a service to interact with the OpenCEP API
+type OpenCEPService struct {
+	URL string
+	BaseHttpService
+}
+
+type OpenCEPResponse struct {
+	Cep         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	Uf          string `json:"uf"`
+	Ibge        string `json:"ibge"`
+}
+
+// NewOpenCEPService creates a new OpenCEPService
+func NewOpenCEPService(client internals.HTTPClient) CEPService {
+	return &OpenCEPService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: OpenCEP_Timeout},
+	}
+}
+
+// GetAddressByCEP returns the address for a given CEP
+func (o *OpenCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	var response OpenCEPResponse
+	err := o.getCEPJSON(ctx, ProviderOpenCEP, urlFor(o.URL, OpenCEP_URL, cep.Digits()), &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.ToAddress(), nil
+}
+
+// ToAddress converts the OpenCEP response into an Address
+func (r *OpenCEPResponse) ToAddress() *Address {
+	return &Address{
+		Cep:          r.Cep,
+		Street:       r.Logradouro,
+		Complement:   r.Complemento,
+		Neighborhood: r.Bairro,
+		City:         r.Localidade,
+		State:        r.Uf,
+		Ibge:         r.Ibge,
+		Provider:     ProviderOpenCEP,
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/stretchr/testify/assert"
+)
+
+const openCEPBody = `{
+	"cep": "01001-000",
+	"logradouro": "Praça da Sé",
+	"complemento": "lado ímpar",
+	"unidade": "",
+	"bairro": "Sé",
+	"localidade": "São Paulo",
+	"uf": "SP",
+	"estado": "São Paulo",
+	"regiao": "Sudeste",
+	"ibge": "3550308"
+}`
+
+func newOpenCEPTestService(t *testing.T) *OpenCEPService {
+	server := newCEPTestServer(t, openCEPBody)
+	return &OpenCEPService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+}
+
+func TestOpenCEPGetAddressByCEP(t *testing.T) {
+	service := newOpenCEPTestService(t)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("01001-000", response.Cep)
+	assert.Equal("Praça da Sé", response.Street)
+	assert.Equal("lado ímpar", response.Complement)
+	assert.Equal("Sé", response.Neighborhood)
+	assert.Equal("São Paulo", response.City)
+	assert.Equal("SP", response.State)
+	assert.Equal("3550308", response.Ibge)
+	assert.Equal(ProviderOpenCEP, response.Provider)
+}
+
+func TestOpenCEPGetAddressErrors(t *testing.T) {
+	service := newOpenCEPTestService(t)
+
+	tests := []struct {
+		cep      string
+		expected error
+	}{
+		{"NotFound", ErrCEPNotFound},
+		{"Unavailable", ErrCEPServiceUnavailable},
+		{"Invalid", ErrInvalidCEP},
+	}
+	for _, test := range tests {
+		t.Run(test.cep, func(t *testing.T) {
+			response, err := service.GetAddressByCEP(context.Background(), cep.CEP(test.cep))
+			assert.Nil(t, response)
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/units"
+)
+
+const (
+	OpenMeteo_GeocodingURL = "https://geocoding-api.open-meteo.com/v1/search"
+	OpenMeteo_ForecastURL  = "https://api.open-meteo.com/v1/forecast"
+	OpenMeteo_Timeout      = 5 * time.Second
+	OpenMeteo_Current      = "temperature_2m,relative_humidity_2m,apparent_temperature,is_day,precipitation,weather_code,cloud_cover,pressure_msl,wind_speed_10m,wind_direction_10m,wind_gusts_10m,visibility,uv_index"
+)
+
+// OpenMeteoService is a service to interact with the Open-Meteo API, which
+// does not require an API key
+type OpenMeteoService struct {
+	GeocodingURL string
+	ForecastURL  string
+	BaseHttpService
+}
+
+// OpenMeteoError is the body of the Open-Meteo error responses
+type OpenMeteoError struct {
+	Error  bool   `json:"error"`
+	Reason string `json:"reason"`
+}
+
+type OpenMeteoGeocodingResponse struct {
+	Results []OpenMeteoPlace `json:"results"`
+}
+
+type OpenMeteoPlace struct {
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code"`
+	Admin1      string  `json:"admin1"`
+	Timezone    string  `json:"timezone"`
+}
+
+type OpenMeteoForecastResponse struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+	Current   struct {
+		Time                string  `json:"time"`
+		Temperature2m       float64 `json:"temperature_2m"`
+		RelativeHumidity2m  int     `json:"relative_humidity_2m"`
+		ApparentTemperature float64 `json:"apparent_temperature"`
+		IsDay               int     `json:"is_day"`
+		Precipitation       float64 `json:"precipitation"`
+		WeatherCode         int     `json:"weather_code"`
+		CloudCover          int     `json:"cloud_cover"`
+		PressureMsl         float64 `json:"pressure_msl"`
+		WindSpeed10m        float64 `json:"wind_speed_10m"`
+		WindDirection10m    int     `json:"wind_direction_10m"`
+		WindGusts10m        float64 `json:"wind_gusts_10m"`
+		Visibility          float64 `json:"visibility"`
+		UvIndex             float64 `json:"uv_index"`
+	} `json:"current"`
+}
+
+// NewOpenMeteoService creates a new OpenMeteoService
+func NewOpenMeteoService(client internals.HTTPClient) WeatherService {
+	return newOpenMeteoService(client)
+}
+
+// NewOpenMeteoGeocoder creates a Geocoder backed by the Open-Meteo geocoding API
+func NewOpenMeteoGeocoder(client internals.HTTPClient) Geocoder {
+	return newOpenMeteoService(client)
+}
+
+func newOpenMeteoService(client internals.HTTPClient) *OpenMeteoService {
+	return &OpenMeteoService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: OpenMeteo_Timeout},
+	}
+}
+
+// GetWeatherByCity returns the current weather for a given city, which may
+// be qualified as "city, state, country"
+func (o *OpenMeteoService) GetWeatherByCity(ctx context.Context, city string) (*Weather, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	name, region, _ := strings.Cut(city, ",")
+	region, _, _ = strings.Cut(region, ",")
+	place, err := o.geocode(ctx, strings.TrimSpace(name), strings.TrimSpace(region))
+	if err != nil {
+		return nil, err
+	}
+
+	weather, err := o.current(ctx, place.Latitude, place.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	weather.Location.Name = place.Name
+	weather.Location.Region = place.Admin1
+	weather.Location.Country = place.Country
+	return weather, nil
+}
+
+// GetWeatherByCoordinates returns the current weather for given coordinates
+func (o *OpenMeteoService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	return o.current(ctx, lat, lon)
+}
+
+// Geocode returns the coordinates of the address city within its state
+func (o *OpenMeteoService) Geocode(ctx context.Context, address *Address) (*Coordinates, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	region := StateNames[strings.ToUpper(address.State)]
+	place, err := o.geocode(ctx, address.City, region)
+	if err != nil {
+		return nil, err
+	}
+	return &Coordinates{Lat: place.Latitude, Lon: place.Longitude}, nil
+}
+
+// geocode returns the first Brazilian place called name, restricted to the
+// given region (state) when it is not empty
+func (o *OpenMeteoService) geocode(ctx context.Context, name, region string) (*OpenMeteoPlace, error) {
+	params := url.Values{}
+	params.Add("name", name)
+	params.Add("count", "10")
+	params.Add("language", "pt")
+	params.Add("countryCode", "BR")
+	var geocoding OpenMeteoGeocodingResponse
+	err := o.getJSON(ctx, urlFor(o.GeocodingURL, OpenMeteo_GeocodingURL), params, &geocoding)
+	if err != nil {
+		return nil, err
+	}
+	for _, place := range geocoding.Results {
+		if region == "" || strings.EqualFold(place.Admin1, region) {
+			return &place, nil
+		}
+	}
+	return nil, ErrLocationNotFound
+}
+
+// current returns the current weather for the given coordinates
+func (o *OpenMeteoService) current(ctx context.Context, lat, lon float64) (*Weather, error) {
+	params := url.Values{}
+	params.Add("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Add("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	params.Add("current", OpenMeteo_Current)
+	params.Add("timezone", "auto")
+	var forecast OpenMeteoForecastResponse
+	err := o.getJSON(ctx, urlFor(o.ForecastURL, OpenMeteo_ForecastURL), params, &forecast)
+	if err != nil {
+		return nil, err
+	}
+	weather, err := forecast.ToWeather()
+	if err != nil {
+		return nil, &UpstreamError{Service: WeatherProviderOpenMeteo, Kind: ErrMalformedResponse, Err: err}
+	}
+	return weather, nil
+}
+
+// getJSON fetches rawURL with params and decodes the response into out
+func (o *OpenMeteoService) getJSON(ctx context.Context, rawURL string, params url.Values, out any) error {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	base.RawQuery = params.Encode()
+	resp, err := o.get(ctx, base.String())
+	if err != nil {
+		log.Println("error getting weather: ", err)
+		return &UpstreamError{Service: WeatherProviderOpenMeteo, Kind: ErrUpstreamUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("error reading response body: ", err)
+		return &UpstreamError{Service: WeatherProviderOpenMeteo, Kind: ErrUpstreamUnavailable, Err: err}
+	} else if resp.StatusCode != 200 {
+		log.Printf("error getting weather: statusCode:%d Response:%s\n", resp.StatusCode, body)
+		var openMeteoError OpenMeteoError
+		json.Unmarshal(body, &openMeteoError)
+		kind := statusKind(resp.StatusCode, ErrUpstreamUnavailable)
+		if kind == nil {
+			kind = ErrUpstreamUnavailable
+		}
+		return &UpstreamError{Service: WeatherProviderOpenMeteo, Kind: kind, StatusCode: resp.StatusCode, Message: openMeteoError.Reason}
+	}
+
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		log.Println("error on Unmarshal response body: ", err)
+		return &UpstreamError{Service: WeatherProviderOpenMeteo, Kind: ErrMalformedResponse, Err: err}
+	}
+	return nil
+}
+
+// ToWeather converts the Open-Meteo response into a Weather
+func (r *OpenMeteoForecastResponse) ToWeather() (*Weather, error) {
+	location, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	observedAt, err := time.ParseInLocation("2006-01-02T15:04", r.Current.Time, location)
+	if err != nil {
+		return nil, err
+	}
+	return &Weather{
+		Location: WeatherLocation{
+			Lat:  r.Latitude,
+			Lon:  r.Longitude,
+			TzID: r.Timezone,
+		},
+		Current: CurrentWeather{
+			ObservedAt: observedAt.UTC(),
+			TempC:      r.Current.Temperature2m,
+			TempF:      units.CelsiusToFahrenheit(r.Current.Temperature2m),
+			FeelsLikeC: r.Current.ApparentTemperature,
+			FeelsLikeF: units.CelsiusToFahrenheit(r.Current.ApparentTemperature),
+			IsDay:      r.Current.IsDay == 1,
+			Condition:  WMOCondition(r.Current.WeatherCode),
+			WindKph:    r.Current.WindSpeed10m,
+			WindDegree: r.Current.WindDirection10m,
+			WindDir:    compassDirection(r.Current.WindDirection10m),
+			GustKph:    r.Current.WindGusts10m,
+			PressureMb: r.Current.PressureMsl,
+			PrecipMm:   r.Current.Precipitation,
+			Humidity:   r.Current.RelativeHumidity2m,
+			Cloud:      r.Current.CloudCover,
+			VisKm:      r.Current.Visibility / 1000,
+			Uv:         r.Current.UvIndex,
+		},
+		Provider: WeatherProviderOpenMeteo,
+	}, nil
+}
+
+var wmoConditions = map[int]string{
+	0:  "Clear sky",
+	1:  "Mainly clear",
+	2:  "Partly cloudy",
+	3:  "Overcast",
+	45: "Fog",
+	48: "Depositing rime fog",
+	51: "Light drizzle",
+	53: "Moderate drizzle",
+	55: "Dense drizzle",
+	56: "Light freezing drizzle",
+	57: "Dense freezing drizzle",
+	61: "Slight rain",
+	63: "Moderate rain",
+	65: "Heavy rain",
+	66: "Light freezing rain",
+	67: "Heavy freezing rain",
+	71: "Slight snow fall",
+	73: "Moderate snow fall",
+	75: "Heavy snow fall",
+	77: "Snow grains",
+	80: "Slight rain showers",
+	81: "Moderate rain showers",
+	82: "Violent rain showers",
+	85: "Slight snow showers",
+	86: "Heavy snow showers",
+	95: "Thunderstorm",
+	96: "Thunderstorm with slight hail",
+	99: "Thunderstorm with heavy hail",
+}
+
+// WMOCondition returns the description of a WMO weather interpretation code
+func WMOCondition(code int) string {
+	if condition, ok := wmoConditions[code]; ok {
+		return condition
+	}
+	return "Unknown"
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	openMeteoGeocodingBody = `{
+		"results": [{
+			"id": 3463237,
+			"name": "Florianópolis",
+			"latitude": -27.59667,
+			"longitude": -48.54917,
+			"country_code": "BR",
+			"timezone": "America/Sao_Paulo",
+			"country": "Brasil",
+			"admin1": "Santa Catarina"
+		}]
+	}`
+	openMeteoHomonymsBody = `{
+		"results": [
+			{"name": "Santa Cruz", "latitude": -17.3895, "longitude": -66.1568, "country_code": "BO", "country": "Bolívia", "admin1": "Cochabamba"},
+			{"name": "Santa Cruz", "latitude": -6.22944, "longitude": -36.02278, "country_code": "BR", "country": "Brasil", "admin1": "Rio Grande do Norte"},
+			{"name": "Santa Cruz", "latitude": -8.24167, "longitude": -40.33444, "country_code": "BR", "country": "Brasil", "admin1": "Pernambuco"}
+		]
+	}`
+	openMeteoForecastBody = `{
+		"latitude": -27.625,
+		"longitude": -48.5,
+		"timezone": "America/Sao_Paulo",
+		"current": {
+			"time": "2024-07-01T16:15",
+			"interval": 900,
+			"temperature_2m": 21,
+			"relative_humidity_2m": 83,
+			"apparent_temperature": 20.4,
+			"is_day": 1,
+			"precipitation": 0.1,
+			"weather_code": 3,
+			"cloud_cover": 100,
+			"pressure_msl": 1008.2,
+			"wind_speed_10m": 13,
+			"wind_direction_10m": 110,
+			"wind_gusts_10m": 20.2,
+			"visibility": 24140,
+			"uv_index": 5
+		}
+	}`
+)
+
+func newOpenMeteoTestService(t *testing.T) *OpenMeteoService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			switch r.URL.Query().Get("name") {
+			case "Florianópolis":
+				w.Write([]byte(openMeteoGeocodingBody))
+			case "Santa Cruz":
+				w.Write([]byte(openMeteoHomonymsBody))
+			case "Unavailable":
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				w.Write([]byte(`{"generationtime_ms": 0.5}`))
+			}
+		case "/forecast":
+			if r.URL.Query().Get("latitude") != "-27.59667" || r.URL.Query().Get("current") != OpenMeteo_Current {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(openMeteoForecastBody))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &OpenMeteoService{
+		GeocodingURL:    server.URL + "/search",
+		ForecastURL:     server.URL + "/forecast",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+}
+
+func TestOpenMeteoGetWeatherByCity(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	result, err := service.GetWeatherByCity(context.Background(), "Florianópolis")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("Florianópolis", result.Location.Name)
+	assert.Equal("Santa Catarina", result.Location.Region)
+	assert.Equal("Brasil", result.Location.Country)
+	assert.Equal(-27.625, result.Location.Lat)
+	assert.Equal("America/Sao_Paulo", result.Location.TzID)
+	assert.Equal(time.Date(2024, 7, 1, 19, 15, 0, 0, time.UTC), result.Current.ObservedAt)
+	assert.Equal(float64(21), result.Current.TempC)
+	assert.Equal(69.8, result.Current.TempF)
+	assert.Equal(20.4, result.Current.FeelsLikeC)
+	assert.InDelta(68.72, result.Current.FeelsLikeF, 0.001)
+	assert.Equal(true, result.Current.IsDay)
+	assert.Equal("Overcast", result.Current.Condition)
+	assert.Equal(float64(13), result.Current.WindKph)
+	assert.Equal("ESE", result.Current.WindDir)
+	assert.Equal(1008.2, result.Current.PressureMb)
+	assert.Equal(83, result.Current.Humidity)
+	assert.Equal(24.14, result.Current.VisKm)
+	assert.Equal(WeatherProviderOpenMeteo, result.Provider)
+}
+
+func TestOpenMeteoGetWeatherByQualifiedCity(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	result, err := service.GetWeatherByCity(context.Background(), "Florianópolis, Santa Catarina, Brazil")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("Florianópolis", result.Location.Name)
+
+	_, err = service.GetWeatherByCity(context.Background(), "Florianópolis, Paraná, Brazil")
+	assert.Equal(ErrLocationNotFound, err)
+}
+
+func TestOpenMeteoGetWeatherByCoordinates(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	result, err := service.GetWeatherByCoordinates(context.Background(), -27.59667, -48.54917)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(-27.625, result.Location.Lat)
+	assert.Equal(float64(21), result.Current.TempC)
+}
+
+func TestOpenMeteoGeocode(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	tests := []struct {
+		state    string
+		expected *Coordinates
+	}{
+		{"RN", &Coordinates{Lat: -6.22944, Lon: -36.02278}},
+		{"PE", &Coordinates{Lat: -8.24167, Lon: -40.33444}},
+		{"", &Coordinates{Lat: -17.3895, Lon: -66.1568}},
+	}
+	for _, test := range tests {
+		t.Run(test.state, func(t *testing.T) {
+			coordinates, err := service.Geocode(context.Background(), &Address{City: "Santa Cruz", State: test.state})
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, coordinates)
+		})
+	}
+
+	_, err := service.Geocode(context.Background(), &Address{City: "Santa Cruz", State: "SP"})
+	assert.Equal(t, ErrLocationNotFound, err)
+}
+
+func TestOpenMeteoLocationNotFound(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	result, err := service.GetWeatherByCity(context.Background(), "Erroropolis")
+
+	assert := assert.New(t)
+	assert.Nil(result)
+	assert.Equal(ErrLocationNotFound, err)
+}
+
+func TestOpenMeteoNot200StatusCode(t *testing.T) {
+	service := newOpenMeteoTestService(t)
+
+	_, err := service.GetWeatherByCity(context.Background(), "Unavailable")
+
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.EqualError(t, err, "openmeteo: upstream service unavailable: status 502")
+}
+
+func TestNewWeatherService(t *testing.T) {
+	assert := assert.New(t)
+
+	service, err := NewWeatherService("", "key", internals.DefaultClient)
+	assert.Nil(err)
+	assert.IsType(&WeatherAPIService{}, service)
+
+	service, err = NewWeatherService("OpenMeteo", "", internals.DefaultClient)
+	assert.Nil(err)
+	assert.IsType(&OpenMeteoService{}, service)
+
+	_, err = NewWeatherService("accuweather", "", internals.DefaultClient)
+	assert.EqualError(err, `unknown weather provider: "accuweather"`)
+}
+
+func TestCompassDirection(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("N", compassDirection(0))
+	assert.Equal("N", compassDirection(355))
+	assert.Equal("ESE", compassDirection(110))
+	assert.Equal("W", compassDirection(-90))
+}
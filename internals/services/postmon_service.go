@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+const (
+	Postmon_URL     = "https://api.postmon.com.br/v1/cep/%s"
+	Postmon_Timeout = 5 * time.Second
+)
+
+// PostmonService is a service to interact with the Postmon API
+type PostmonService struct {
+	URL string
+	BaseHttpService
+}
+
+type PostmonResponse struct {
+	Cep         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Cidade      string `json:"cidade"`
+	Estado      string `json:"estado"`
+	CidadeInfo  struct {
+		CodigoIbge string `json:"codigo_ibge"`
+	} `json:"cidade_info"`
+}
+
+// NewPostmonService creates a new PostmonService
+func NewPostmonService(client internals.HTTPClient) CEPService {
+	return &PostmonService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: Postmon_Timeout},
+	}
+}
+
+// GetAddressByCEP returns the address for a given CEP
+func (p *PostmonService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	var response PostmonResponse
+	err := p.getCEPJSON(ctx, ProviderPostmon, urlFor(p.URL, Postmon_URL, cep.Digits()), &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.ToAddress(), nil
+}
+
+// ToAddress converts the Postmon response into an Address
+func (r *PostmonResponse) ToAddress() *Address {
+	return &Address{
+		Cep:          r.Cep,
+		Street:       r.Logradouro,
+		Complement:   r.Complemento,
+		Neighborhood: r.Bairro,
+		City:         r.Cidade,
+		State:        r.Estado,
+		Ibge:         r.CidadeInfo.CodigoIbge,
+		Provider:     ProviderPostmon,
+	}
+}
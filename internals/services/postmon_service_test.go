@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+	"github.com/stretchr/testify/assert"
+)
+
+const postmonBody = `{
+	"complemento": "lado ímpar",
+	"bairro": "Sé",
+	"cidade": "São Paulo",
+	"logradouro": "Praça da Sé",
+	"estado_info": {
+		"area_km2": "248.219,485",
+		"codigo_ibge": "35",
+		"nome": "São Paulo"
+	},
+	"cep": "01001000",
+	"cidade_info": {
+		"area_km2": "1521,11",
+		"codigo_ibge": "3550308"
+	},
+	"estado": "SP"
+}`
+
+func newPostmonTestService(t *testing.T) *PostmonService {
+	server := newCEPTestServer(t, postmonBody)
+	return &PostmonService{
+		URL:             server.URL + "/%s",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+}
+
+func TestPostmonGetAddressByCEP(t *testing.T) {
+	service := newPostmonTestService(t)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("01001000", response.Cep)
+	assert.Equal("Praça da Sé", response.Street)
+	assert.Equal("lado ímpar", response.Complement)
+	assert.Equal("Sé", response.Neighborhood)
+	assert.Equal("São Paulo", response.City)
+	assert.Equal("SP", response.State)
+	assert.Equal("3550308", response.Ibge)
+	assert.Equal(ProviderPostmon, response.Provider)
+}
+
+func TestPostmonGetAddressErrors(t *testing.T) {
+	service := newPostmonTestService(t)
+
+	tests := []struct {
+		cep      string
+		expected error
+	}{
+		{"NotFound", ErrCEPNotFound},
+		{"Unavailable", ErrCEPServiceUnavailable},
+		{"Invalid", ErrInvalidCEP},
+	}
+	for _, test := range tests {
+		t.Run(test.cep, func(t *testing.T) {
+			response, err := service.GetAddressByCEP(context.Background(), cep.CEP(test.cep))
+			assert.Nil(t, response)
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
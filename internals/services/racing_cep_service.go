@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
+)
+
+// raceMetrics exposes the races won by each provider on /debug/vars
+var raceMetrics = expvar.NewMap("cep_races")
+
+// RacingCEPService queries all its providers concurrently and returns the
+// first valid address, cancelling the requests still in flight
+type RacingCEPService struct {
+	Providers []CEPProvider
+
+	mu    sync.Mutex
+	last  RaceResult
+	stats map[string]ProviderStats
+}
+
+// RaceResult describes which provider won a race and how long it took
+type RaceResult struct {
+	Provider string
+	Latency  time.Duration
+}
+
+// ProviderStats aggregates the races won by a provider
+type ProviderStats struct {
+	Wins        int
+	LastLatency time.Duration
+}
+
+type raceAnswer struct {
+	provider string
+	address  *Address
+	err      error
+	latency  time.Duration
+}
+
+// NewRacingCEPService creates a new RacingCEPService
+func NewRacingCEPService(providers ...CEPProvider) *RacingCEPService {
+	return &RacingCEPService{Providers: providers}
+}
+
+// GetAddressByCEP returns the address from the fastest provider
+func (r *RacingCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan raceAnswer, len(r.Providers))
+	start := time.Now()
+	for _, provider := range r.Providers {
+		go func() {
+			address, err := provider.Service.GetAddressByCEP(ctx, cep)
+			answers <- raceAnswer{provider.Name, address, err, time.Since(start)}
+		}()
+	}
+
+	var finalErr, lastErr error
+	for range r.Providers {
+		answer := <-answers
+		if answer.err == nil {
+			answer.err = validateAddress(answer.address, cep)
+		}
+		if answer.err == nil {
+			r.record(answer.provider, answer.latency)
+			log.Printf("CEP %s resolved by %s in %s\n", cep, answer.provider, answer.latency)
+			return answer.address, nil
+		}
+		if isFinalCEPError(answer.err) {
+			finalErr = answer.err
+		} else {
+			lastErr = answer.err
+		}
+	}
+
+	switch {
+	case finalErr != nil:
+		return nil, finalErr
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case lastErr == nil:
+		return nil, ErrCEPServiceUnavailable
+	case errors.Is(lastErr, ErrCEPServiceUnavailable):
+		return nil, lastErr
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrCEPServiceUnavailable, lastErr)
+	}
+}
+
+// LastResult returns the provider that won the latest race
+func (r *RacingCEPService) LastResult() RaceResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Stats returns a copy of the races won by each provider
+func (r *RacingCEPService) Stats() map[string]ProviderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make(map[string]ProviderStats, len(r.stats))
+	for name, stat := range r.stats {
+		stats[name] = stat
+	}
+	return stats
+}
+
+func (r *RacingCEPService) record(provider string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats == nil {
+		r.stats = make(map[string]ProviderStats)
+	}
+	stat := r.stats[provider]
+	stat.Wins++
+	stat.LastLatency = latency
+	r.stats[provider] = stat
+	r.last = RaceResult{Provider: provider, Latency: latency}
+
+	raceMetrics.Add(provider+".wins", 1)
+	lastLatency := new(expvar.Float)
+	lastLatency.Set(latency.Seconds() * 1000)
+	raceMetrics.Set(provider+".last_latency_ms", lastLatency)
+	winner := new(expvar.String)
+	winner.Set(provider)
+	raceMetrics.Set("last_winner", winner)
+}
+
+// validateAddress checks that a provider answer is usable for cep
+func validateAddress(address *Address, cep cep.CEP) error {
+	if address == nil || address.City == "" || address.State == "" {
+		return fmt.Errorf("incomplete address for CEP %s", cep)
+	}
+	if digits(address.Cep) != cep.Digits() {
+		return fmt.Errorf("address for CEP %s does not match %s", address.Cep, cep)
+	}
+	return nil
+}
+
+// digits returns only the digits of value
+func digits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, value)
+}
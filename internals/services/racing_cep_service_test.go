@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newDelayedTestServer answers with status and body after delay, reporting
+// on cancelled when the client gives up before that
+func newDelayedTestServer(t *testing.T, delay time.Duration, status int, body string, cancelled chan<- struct{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		case <-r.Context().Done():
+			if cancelled != nil {
+				cancelled <- struct{}{}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// raceWins returns the races won by provider as published on /debug/vars
+func raceWins(provider string) int64 {
+	if wins, ok := raceMetrics.Get(provider + ".wins").(*expvar.Int); ok {
+		return wins.Value()
+	}
+	return 0
+}
+
+func TestRacingCEPServiceFastestWins(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+	slow := newDelayedTestServer(t, 2*time.Second, http.StatusOK, CEPBody, cancelled)
+	fast := newDelayedTestServer(t, 0, http.StatusOK, brasilAPIBody, nil)
+
+	service := NewRacingCEPService(
+		CEPProvider{ProviderViaCEP, &ViaCEPService{URL: slow.URL + "/%s", BaseHttpService: BaseHttpService{Client: slow.Client()}}},
+		CEPProvider{ProviderBrasilAPI, &BrasilAPIService{URL: fast.URL + "/%s", BaseHttpService: BaseHttpService{Client: fast.Client()}}},
+	)
+
+	wins := raceWins(ProviderBrasilAPI)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(ProviderBrasilAPI, response.Provider)
+	assert.Equal(ProviderBrasilAPI, service.LastResult().Provider)
+	assert.Equal(wins+1, raceWins(ProviderBrasilAPI))
+	assert.Equal(`"brasilapi"`, raceMetrics.Get("last_winner").String())
+	assert.Less(service.LastResult().Latency, 2*time.Second)
+	assert.Equal(1, service.Stats()[ProviderBrasilAPI].Wins)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("slow provider request was not cancelled")
+	}
+}
+
+func TestRacingCEPServiceSkipsInvalidAnswers(t *testing.T) {
+	wrongCEP := strings.Replace(brasilAPIBody, `"01001000"`, `"02002000"`, 1)
+	wrong := newDelayedTestServer(t, 0, http.StatusOK, wrongCEP, nil)
+	right := newDelayedTestServer(t, 50*time.Millisecond, http.StatusOK, openCEPBody, nil)
+
+	service := NewRacingCEPService(
+		CEPProvider{ProviderBrasilAPI, &BrasilAPIService{URL: wrong.URL + "/%s", BaseHttpService: BaseHttpService{Client: wrong.Client()}}},
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: right.URL + "/%s", BaseHttpService: BaseHttpService{Client: right.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(ProviderOpenCEP, response.Provider)
+}
+
+func TestRacingCEPServiceNotFound(t *testing.T) {
+	notFound := newDelayedTestServer(t, 0, http.StatusNotFound, ``, nil)
+	down := newDelayedTestServer(t, 0, http.StatusServiceUnavailable, ``, nil)
+
+	service := NewRacingCEPService(
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: notFound.URL + "/%s", BaseHttpService: BaseHttpService{Client: notFound.Client()}}},
+		CEPProvider{ProviderPostmon, &PostmonService{URL: down.URL + "/%s", BaseHttpService: BaseHttpService{Client: down.Client()}}},
+	)
+
+	response, err := service.GetAddressByCEP(context.Background(), "99999999")
+
+	assert := assert.New(t)
+	assert.Nil(response)
+	assert.Equal(ErrCEPNotFound, err)
+	assert.Empty(service.Stats())
+}
+
+func TestRacingCEPServiceAllProvidersDown(t *testing.T) {
+	down := newDelayedTestServer(t, 0, http.StatusBadGateway, ``, nil)
+
+	service := NewRacingCEPService(
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: down.URL + "/%s", BaseHttpService: BaseHttpService{Client: down.Client()}}},
+		CEPProvider{ProviderPostmon, &PostmonService{URL: down.URL + "/%s", BaseHttpService: BaseHttpService{Client: down.Client()}}},
+	)
+
+	_, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert.ErrorIs(t, err, ErrCEPServiceUnavailable)
+}
+
+func TestRacingCEPServiceConcurrentRaces(t *testing.T) {
+	fast := newDelayedTestServer(t, 0, http.StatusOK, brasilAPIBody, nil)
+	slow := newDelayedTestServer(t, 20*time.Millisecond, http.StatusOK, openCEPBody, nil)
+
+	service := NewRacingCEPService(
+		CEPProvider{ProviderBrasilAPI, &BrasilAPIService{URL: fast.URL + "/%s", BaseHttpService: BaseHttpService{Client: fast.Client()}}},
+		CEPProvider{ProviderOpenCEP, &OpenCEPService{URL: slow.URL + "/%s", BaseHttpService: BaseHttpService{Client: slow.Client()}}},
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.GetAddressByCEP(context.Background(), "01001000")
+			assert.Nil(t, err)
+			service.LastResult()
+		}()
+	}
+	wg.Wait()
+
+	stats := service.Stats()
+	assert.Equal(t, 10, stats[ProviderBrasilAPI].Wins+stats[ProviderOpenCEP].Wins)
+}
+
+func TestNewCEPServiceWithStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	service, err := NewCEPServiceWithStrategy("race", nil)
+	assert.Nil(err)
+	assert.IsType(&RacingCEPService{}, service)
+
+	service, err = NewCEPServiceWithStrategy("", nil)
+	assert.Nil(err)
+	assert.IsType(&FallbackCEPService{}, service)
+
+	_, err = NewCEPServiceWithStrategy("random", nil)
+	assert.EqualError(err, `unknown CEP strategy: "random"`)
+}
@@ -1,7 +1,77 @@
 package services
 
-import "github.com/rcbadiale/go-cloud-run/internals"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+)
 
 type BaseHttpService struct {
-	Client internals.HTTPClient
+	Client  internals.HTTPClient
+	Timeout time.Duration
+}
+
+// withTimeout bounds ctx by the service timeout, when one is configured
+func (b *BaseHttpService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, b.Timeout)
+}
+
+// get performs a GET request to url bound to ctx
+func (b *BaseHttpService) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return b.Client.Do(req)
+}
+
+// getCEPJSON fetches url and decodes the response into out, mapping the
+// status codes shared by the CEP providers to the package errors
+func (b *BaseHttpService) getCEPJSON(ctx context.Context, service, url string, out any) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := b.get(ctx, url)
+	if err != nil {
+		log.Println("error getting address by CEP: ", err)
+		return &UpstreamError{Service: service, Kind: ErrCEPServiceUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("error reading response body: ", err)
+		return &UpstreamError{Service: service, Kind: ErrCEPServiceUnavailable, Err: err}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrCEPNotFound
+	} else if kind := statusKind(resp.StatusCode, ErrCEPServiceUnavailable); kind != nil {
+		return &UpstreamError{Service: service, Kind: kind, StatusCode: resp.StatusCode}
+	} else if resp.StatusCode != http.StatusOK {
+		return ErrInvalidCEP
+	}
+
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		log.Println("error on Unmarshal response body: ", err, string(body))
+		return &UpstreamError{Service: service, Kind: ErrMalformedResponse, Err: err}
+	}
+	return nil
+}
+
+// urlFor formats the service URL, falling back to the default format
+func urlFor(format, fallback string, args ...any) string {
+	if format == "" {
+		format = fallback
+	}
+	return fmt.Sprintf(format, args...)
 }
@@ -1,8 +1,86 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 var (
-	ErrInvalidCEP  = errors.New("invalid CEP provided")
-	ErrCEPNotFound = errors.New("CEP not found")
+	ErrInvalidCEP          = errors.New("invalid CEP provided")
+	ErrCEPNotFound         = errors.New("CEP not found")
+	ErrLocationNotFound    = errors.New("location not found")
+	ErrUpstreamUnavailable = errors.New("upstream service unavailable")
+	ErrRateLimited         = errors.New("upstream rate limit exceeded")
+	ErrUnauthorized        = errors.New("upstream rejected the API key")
+	ErrMalformedResponse   = errors.New("malformed upstream response")
+
+	// ErrCEPServiceUnavailable is the ErrUpstreamUnavailable of the CEP
+	// providers
+	ErrCEPServiceUnavailable error = &kindError{"CEP service unavailable", ErrUpstreamUnavailable}
 )
+
+// kindError is an error kind that also matches a broader parent kind
+type kindError struct {
+	msg    string
+	parent error
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.parent }
+
+// UpstreamError is a failure of an upstream service, matching both its Kind
+// and its cause with errors.Is and errors.As
+type UpstreamError struct {
+	// Service is the name of the upstream service
+	Service string
+	// Kind is one of the package errors, e.g. ErrRateLimited
+	Kind error
+	// StatusCode is the HTTP status of the upstream response, if any
+	StatusCode int
+	// Code and Message are the error reported by the upstream, if any
+	Code    int
+	Message string
+	// Err is the cause of the failure, if any
+	Err error
+}
+
+func (e *UpstreamError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Service, e.Kind)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(": status %d", e.StatusCode)
+	}
+	if e.Code != 0 {
+		msg += fmt.Sprintf(": code %d", e.Code)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UpstreamError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// statusKind returns the error kind of an upstream status, using
+// unavailable for server errors, or nil when the status is not a failure of
+// the upstream itself
+func statusKind(status int, unavailable error) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return unavailable
+	}
+	return nil
+}
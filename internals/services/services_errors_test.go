@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamErrorMatchesKindAndCause(t *testing.T) {
+	assert := assert.New(t)
+	cause := &internals.CircuitOpenError{Name: "viacep"}
+	err := error(&UpstreamError{Service: ProviderViaCEP, Kind: ErrCEPServiceUnavailable, Err: cause})
+
+	assert.ErrorIs(err, ErrCEPServiceUnavailable)
+	assert.ErrorIs(err, ErrUpstreamUnavailable)
+	assert.ErrorIs(err, internals.ErrCircuitOpen)
+	assert.NotErrorIs(err, ErrRateLimited)
+	var circuitErr *internals.CircuitOpenError
+	assert.ErrorAs(err, &circuitErr)
+	var upstreamErr *UpstreamError
+	assert.ErrorAs(err, &upstreamErr)
+	assert.Equal(ProviderViaCEP, upstreamErr.Service)
+}
+
+func TestUpstreamErrorMessage(t *testing.T) {
+	assert := assert.New(t)
+	err := &UpstreamError{
+		Service:    WeatherProviderWeatherAPI,
+		Kind:       ErrLocationNotFound,
+		StatusCode: http.StatusBadRequest,
+		Code:       WeatherAPI_ErrNoLocation,
+		Message:    "No matching location found.",
+	}
+
+	assert.EqualError(err, "weatherapi: location not found: status 400: code 1006: No matching location found.")
+	assert.EqualError(&UpstreamError{Service: "x", Kind: ErrMalformedResponse, Err: errors.New("EOF")}, "x: malformed upstream response: EOF")
+}
+
+func TestStatusKind(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(ErrUnauthorized, statusKind(http.StatusUnauthorized, ErrUpstreamUnavailable))
+	assert.Equal(ErrUnauthorized, statusKind(http.StatusForbidden, ErrUpstreamUnavailable))
+	assert.Equal(ErrRateLimited, statusKind(http.StatusTooManyRequests, ErrUpstreamUnavailable))
+	assert.Equal(ErrCEPServiceUnavailable, statusKind(http.StatusBadGateway, ErrCEPServiceUnavailable))
+	assert.Nil(statusKind(http.StatusBadRequest, ErrUpstreamUnavailable))
+	assert.Nil(statusKind(http.StatusOK, ErrUpstreamUnavailable))
+}
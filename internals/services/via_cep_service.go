@@ -1,23 +1,27 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
-	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+	"github.com/rcbadiale/go-cloud-run/internals/cep"
 )
 
 const (
-	ViaCEP_URL = "https://viacep.com.br/ws/%s/json/"
+	ViaCEP_URL       = "https://viacep.com.br/ws/%s/json/"
+	ViaCEP_SearchURL = "https://viacep.com.br/ws/%s/%s/%s/json/"
+	ViaCEP_Timeout   = 5 * time.Second
 )
 
-type CEPService interface {
-	GetAddressByCEP(cep string) (*ViaCEPResponse, error)
-}
-
 // ViaCEPService is a service to interact with the ViaCEP API
 type ViaCEPService struct {
+	URL       string
+	SearchURL string
 	BaseHttpService
 }
 
@@ -36,24 +40,30 @@ type ViaCEPResponse struct {
 }
 
 // NewViaCEPService creates a new ViaCEPService
-func NewViaCEPService() CEPService {
-	return &ViaCEPService{BaseHttpService{Client: &http.Client{}}}
+func NewViaCEPService(client internals.HTTPClient) CEPService {
+	return &ViaCEPService{
+		BaseHttpService: BaseHttpService{Client: client, Timeout: ViaCEP_Timeout},
+	}
 }
 
 // GetAddressByCEP returns the address for a given CEP
-func (v *ViaCEPService) GetAddressByCEP(cep string) (*ViaCEPResponse, error) {
+func (v *ViaCEPService) GetAddressByCEP(ctx context.Context, cep cep.CEP) (*Address, error) {
+	ctx, cancel := v.withTimeout(ctx)
+	defer cancel()
 
-	resp, err := v.Client.Get(fmt.Sprintf(ViaCEP_URL, cep))
+	resp, err := v.get(ctx, urlFor(v.URL, ViaCEP_URL, cep.Digits()))
 	if err != nil {
 		log.Println("error getting address by CEP: ", err)
-		return nil, err
+		return nil, &UpstreamError{Service: ProviderViaCEP, Kind: ErrCEPServiceUnavailable, Err: err}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("error reading response body: ", err)
-		return nil, err
+		return nil, &UpstreamError{Service: ProviderViaCEP, Kind: ErrCEPServiceUnavailable, Err: err}
+	} else if kind := statusKind(resp.StatusCode, ErrCEPServiceUnavailable); kind != nil {
+		return nil, &UpstreamError{Service: ProviderViaCEP, Kind: kind, StatusCode: resp.StatusCode}
 	} else if resp.StatusCode != 200 {
 		return nil, ErrInvalidCEP
 	}
@@ -62,11 +72,45 @@ func (v *ViaCEPService) GetAddressByCEP(cep string) (*ViaCEPResponse, error) {
 	err = json.Unmarshal(body, &viaCepResponse)
 	if err != nil {
 		log.Println("error on Unmarshal response body: ", err, string(body))
-		return nil, err
+		return nil, &UpstreamError{Service: ProviderViaCEP, Kind: ErrMalformedResponse, Err: err}
 	} else if viaCepResponse.Erro == "true" {
 		log.Printf("error invalid address by CEP: %v\n", string(body))
 		return nil, ErrCEPNotFound
 	}
 
-	return &viaCepResponse, nil
+	return viaCepResponse.ToAddress(), nil
+}
+
+// SearchCEP returns the addresses, along with their CEPs, matching the UF,
+// city and street of query. ViaCEP requires city and street to have at least
+// 3 characters and answers at most 50 addresses
+func (v *ViaCEPService) SearchCEP(ctx context.Context, query AddressQuery) ([]Address, error) {
+	searchURL := urlFor(v.SearchURL, ViaCEP_SearchURL, url.PathEscape(query.UF), url.PathEscape(query.City), url.PathEscape(query.Street))
+	var responses []ViaCEPResponse
+	err := v.getCEPJSON(ctx, ProviderViaCEP, searchURL, &responses)
+	if err != nil {
+		return nil, err
+	} else if len(responses) == 0 {
+		return nil, ErrCEPNotFound
+	}
+	addresses := make([]Address, 0, len(responses))
+	for _, response := range responses {
+		addresses = append(addresses, *response.ToAddress())
+	}
+	return addresses, nil
+}
+
+// ToAddress converts the ViaCEP response into an Address
+func (r *ViaCEPResponse) ToAddress() *Address {
+	return &Address{
+		Cep:          r.Cep,
+		Street:       r.Logradouro,
+		Complement:   r.Complemento,
+		Neighborhood: r.Bairro,
+		City:         r.Localidade,
+		State:        r.Uf,
+		Ibge:         r.Ibge,
+		Ddd:          r.Ddd,
+		Provider:     ProviderViaCEP,
+	}
 }
@@ -2,13 +2,17 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,7 +32,8 @@ const CEPBody = `{
 
 type mockViaCepHTTPClient struct{}
 
-func (m *mockViaCepHTTPClient) Get(url string) (*http.Response, error) {
+func (m *mockViaCepHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
 	re := regexp.MustCompile(`https://viacep.com.br/ws/(\w+)/json/`)
 	match := re.FindStringSubmatch(url)
 	log.Println("WOWOWO" + match[0])
@@ -47,6 +52,13 @@ func (m *mockViaCepHTTPClient) Get(url string) (*http.Response, error) {
 			Header:     make(http.Header),
 		}
 		return response, nil
+	case "Unavailable":
+		response := &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(bytes.NewBufferString(``)),
+			Header:     make(http.Header),
+		}
+		return response, nil
 	case "BrokenReader":
 		response := &http.Response{
 			StatusCode: 200,
@@ -82,22 +94,21 @@ func TestGetAddressByCEP(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	response, err := service.GetAddressByCEP("01001000")
+	response, err := service.GetAddressByCEP(context.Background(), "01001000")
 
 	// Assert there was no error
 	assert.Nil(t, err)
 
 	// Assert the response fields are correct
 	assert.Equal(t, "01001-000", response.Cep)
-	assert.Equal(t, "Praça da Sé", response.Logradouro)
-	assert.Equal(t, "lado ímpar", response.Complemento)
-	assert.Equal(t, "Sé", response.Bairro)
-	assert.Equal(t, "São Paulo", response.Localidade)
-	assert.Equal(t, "SP", response.Uf)
+	assert.Equal(t, "Praça da Sé", response.Street)
+	assert.Equal(t, "lado ímpar", response.Complement)
+	assert.Equal(t, "Sé", response.Neighborhood)
+	assert.Equal(t, "São Paulo", response.City)
+	assert.Equal(t, "SP", response.State)
 	assert.Equal(t, "3550308", response.Ibge)
-	assert.Equal(t, "1004", response.Gia)
 	assert.Equal(t, "11", response.Ddd)
-	assert.Equal(t, "7107", response.Siafi)
+	assert.Equal(t, ProviderViaCEP, response.Provider)
 }
 
 func TestGetAddressReaderError(t *testing.T) {
@@ -106,10 +117,11 @@ func TestGetAddressReaderError(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	_, err := service.GetAddressByCEP("BrokenReader")
+	_, err := service.GetAddressByCEP(context.Background(), "BrokenReader")
 
 	assert := assert.New(t)
-	assert.Equal("failed reading", err.Error())
+	assert.ErrorIs(err, ErrCEPServiceUnavailable)
+	assert.Equal("viacep: CEP service unavailable: failed reading", err.Error())
 }
 
 func TestGetAddressNot200StatusCode(t *testing.T) {
@@ -118,23 +130,36 @@ func TestGetAddressNot200StatusCode(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	_, err := service.GetAddressByCEP("Erroropolis")
+	_, err := service.GetAddressByCEP(context.Background(), "Erroropolis")
 
 	assert := assert.New(t)
 	assert.Equal(fmt.Errorf("invalid CEP provided"), err)
 
 }
 
+func TestGetAddressUnavailable(t *testing.T) {
+
+	service := &ViaCEPService{
+		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
+	}
+
+	_, err := service.GetAddressByCEP(context.Background(), "Unavailable")
+
+	assert := assert.New(t)
+	assert.ErrorIs(err, ErrCEPServiceUnavailable)
+}
+
 func TestGetAddressBadBody(t *testing.T) {
 
 	service := &ViaCEPService{
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	_, err := service.GetAddressByCEP("RequestFail")
+	_, err := service.GetAddressByCEP(context.Background(), "RequestFail")
 
 	assert := assert.New(t)
-	assert.Equal("error getting weather: 400", err.Error())
+	assert.ErrorIs(err, ErrCEPServiceUnavailable)
+	assert.Equal("viacep: CEP service unavailable: error getting weather: 400", err.Error())
 }
 
 func TestGetAddressUnmarshalError(t *testing.T) {
@@ -143,11 +168,12 @@ func TestGetAddressUnmarshalError(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	resp, err := service.GetAddressByCEP("UnmarshalError")
+	resp, err := service.GetAddressByCEP(context.Background(), "UnmarshalError")
 
 	assert := assert.New(t)
 	assert.Nil(resp)
-	assert.Equal("invalid character '0' after object key:value pair", err.Error())
+	assert.ErrorIs(err, ErrMalformedResponse)
+	assert.Equal("viacep: malformed upstream response: invalid character '0' after object key:value pair", err.Error())
 }
 
 func TestGetAddressErrorinResponse(t *testing.T) {
@@ -156,9 +182,106 @@ func TestGetAddressErrorinResponse(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockViaCepHTTPClient{}},
 	}
 
-	resp, err := service.GetAddressByCEP("BadValue")
+	resp, err := service.GetAddressByCEP(context.Background(), "BadValue")
 
 	assert := assert.New(t)
 	assert.Nil(resp)
 	assert.Equal("CEP not found", err.Error())
 }
+
+func TestGetAddressCancelledContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	service := &ViaCEPService{
+		BaseHttpService: BaseHttpService{Client: &redirectClient{target: server.URL, client: server.Client()}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp, err := service.GetAddressByCEP(ctx, "01001000")
+
+	assert := assert.New(t)
+	assert.Nil(resp)
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestGetAddressTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := &ViaCEPService{
+		BaseHttpService: BaseHttpService{
+			Client:  &redirectClient{target: server.URL, client: server.Client()},
+			Timeout: 50 * time.Millisecond,
+		},
+	}
+
+	start := time.Now()
+	_, err := service.GetAddressByCEP(context.Background(), "01001000")
+
+	assert := assert.New(t)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Less(time.Since(start), time.Second)
+}
+
+// redirectClient sends every request to target, keeping path and query
+type redirectClient struct {
+	target string
+	client *http.Client
+}
+
+func (c *redirectClient) Do(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(c.target + req.URL.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	req.Host = u.Host
+	return c.client.Do(req)
+}
+
+func TestSearchCEP(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		switch r.URL.Path {
+		case "/ws/SP/São Paulo/Praça da Sé/json/":
+			w.Write([]byte(`[` + CEPBody + `, ` + strings.Replace(CEPBody, `"01001-000"`, `"01001-001"`, 1) + `]`))
+		case "/ws/SP/São Paulo/Nowhere/json/":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	service := &ViaCEPService{
+		SearchURL:       server.URL + "/ws/%s/%s/%s/json/",
+		BaseHttpService: BaseHttpService{Client: server.Client()},
+	}
+
+	addresses, err := service.SearchCEP(context.Background(), AddressQuery{UF: "SP", City: "São Paulo", Street: "Praça da Sé"})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("/ws/SP/S%C3%A3o%20Paulo/Pra%C3%A7a%20da%20S%C3%A9/json/", path)
+	assert.Len(addresses, 2)
+	assert.Equal("01001-000", addresses[0].Cep)
+	assert.Equal("01001-001", addresses[1].Cep)
+	assert.Equal("Praça da Sé", addresses[1].Street)
+	assert.Equal(ProviderViaCEP, addresses[1].Provider)
+
+	_, err = service.SearchCEP(context.Background(), AddressQuery{UF: "SP", City: "São Paulo", Street: "Nowhere"})
+	assert.ErrorIs(err, ErrCEPNotFound)
+	_, err = service.SearchCEP(context.Background(), AddressQuery{UF: "SP", City: "São Paulo", Street: "x/y"})
+	assert.ErrorIs(err, ErrInvalidCEP)
+}
@@ -1,26 +1,42 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
 )
 
 const (
-	API_KEY        = "<YOU_API_KEY>"
-	WeatherAPI_URL = "https://api.weatherapi.com/v1/current.json"
+	API_KEY                = "<YOU_API_KEY>"
+	WeatherAPI_URL         = "https://api.weatherapi.com/v1/current.json"
+	WeatherAPI_ForecastURL = "https://api.weatherapi.com/v1/forecast.json"
+	WeatherAPI_HistoryURL  = "https://api.weatherapi.com/v1/history.json"
+	WeatherAPI_Timeout     = 5 * time.Second
 )
 
-type WeatherService interface {
-	GetWeatherByCity(city string) (*WeatherAPIResponse, error)
-}
+// WeatherAPI error codes, see https://www.weatherapi.com/docs/#intro-error-codes
+const (
+	WeatherAPI_ErrKeyNotProvided = 1002
+	WeatherAPI_ErrNoLocation     = 1006
+	WeatherAPI_ErrInvalidKey     = 2006
+	WeatherAPI_ErrQuotaExceeded  = 2007
+	WeatherAPI_ErrKeyDisabled    = 2008
+	WeatherAPI_ErrNoAccess       = 2009
+	WeatherAPI_ErrInternal       = 9999
+)
 
 // WeatherAPIService is a service to interact with the WeatherAPI API
 type WeatherAPIService struct {
-	apiKey string
+	apiKey      string
+	URL         string
+	ForecastURL string
+	HistoryURL  string
 	BaseHttpService
 }
 
@@ -54,6 +70,14 @@ type WeatherAPIResponseCurrent struct {
 	GustKph    float64 `json:"gust_kph"`
 }
 
+// WeatherAPIError is the body of the WeatherAPI error responses
+type WeatherAPIError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 type WeatherAPIResponse struct {
 	Location struct {
 		Name           string  `json:"name"`
@@ -68,43 +92,276 @@ type WeatherAPIResponse struct {
 	Current WeatherAPIResponseCurrent `json:"current"`
 }
 
+type WeatherAPIResponseCondition struct {
+	Text string `json:"text"`
+	Icon string `json:"icon"`
+	Code int    `json:"code"`
+}
+
+type WeatherAPIResponseForecastDay struct {
+	Date      string `json:"date"`
+	DateEpoch int    `json:"date_epoch"`
+	Day       struct {
+		MaxtempC          float64                     `json:"maxtemp_c"`
+		MaxtempF          float64                     `json:"maxtemp_f"`
+		MintempC          float64                     `json:"mintemp_c"`
+		MintempF          float64                     `json:"mintemp_f"`
+		AvgtempC          float64                     `json:"avgtemp_c"`
+		AvgtempF          float64                     `json:"avgtemp_f"`
+		TotalprecipMm     float64                     `json:"totalprecip_mm"`
+		DailyChanceOfRain int                         `json:"daily_chance_of_rain"`
+		Condition         WeatherAPIResponseCondition `json:"condition"`
+	} `json:"day"`
+	Hour []struct {
+		TimeEpoch    int                         `json:"time_epoch"`
+		Time         string                      `json:"time"`
+		TempC        float64                     `json:"temp_c"`
+		TempF        float64                     `json:"temp_f"`
+		IsDay        int                         `json:"is_day"`
+		ChanceOfRain int                         `json:"chance_of_rain"`
+		Condition    WeatherAPIResponseCondition `json:"condition"`
+	} `json:"hour"`
+}
+
+type WeatherAPIForecastResponse struct {
+	WeatherAPIResponse
+	Forecast struct {
+		Forecastday []WeatherAPIResponseForecastDay `json:"forecastday"`
+	} `json:"forecast"`
+}
+
 // NewWeatherAPIService creates a new WeatherAPIService
-func NewWeatherAPIService(apiKey string) WeatherService {
+func NewWeatherAPIService(apiKey string, client internals.HTTPClient) WeatherService {
 	return &WeatherAPIService{
-		apiKey,
-		BaseHttpService{Client: &http.Client{}},
+		apiKey:          apiKey,
+		BaseHttpService: BaseHttpService{Client: client, Timeout: WeatherAPI_Timeout},
+	}
+}
+
+// GetWeatherByCity returns the current weather for a given city
+func (w *WeatherAPIService) GetWeatherByCity(ctx context.Context, city string) (*Weather, error) {
+	return w.current(ctx, city)
+}
+
+// GetWeatherByCoordinates returns the current weather for given coordinates
+func (w *WeatherAPIService) GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error) {
+	return w.current(ctx, formatCoordinates(lat, lon))
+}
+
+// GetForecastByCity returns the forecast for the next days of a given city
+func (w *WeatherAPIService) GetForecastByCity(ctx context.Context, city string, days int) (*Forecast, error) {
+	return w.forecast(ctx, city, days)
+}
+
+// GetForecastByCoordinates returns the forecast for the next days of given
+// coordinates
+func (w *WeatherAPIService) GetForecastByCoordinates(ctx context.Context, lat, lon float64, days int) (*Forecast, error) {
+	return w.forecast(ctx, formatCoordinates(lat, lon), days)
+}
+
+// GetHistoryByCity returns the weather observed from and to the given days
+// in a given city
+func (w *WeatherAPIService) GetHistoryByCity(ctx context.Context, city string, from, to time.Time) (*History, error) {
+	return w.history(ctx, city, from, to)
+}
+
+// GetHistoryByCoordinates returns the weather observed from and to the given
+// days at given coordinates
+func (w *WeatherAPIService) GetHistoryByCoordinates(ctx context.Context, lat, lon float64, from, to time.Time) (*History, error) {
+	return w.history(ctx, formatCoordinates(lat, lon), from, to)
+}
+
+// current returns the current weather for a WeatherAPI "q" parameter
+func (w *WeatherAPIService) current(ctx context.Context, query string) (*Weather, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	var weatherResponse WeatherAPIResponse
+	err := w.getJSON(ctx, urlFor(w.URL, WeatherAPI_URL), params, &weatherResponse)
+	if err != nil {
+		return nil, err
 	}
+	return weatherResponse.ToWeather(), nil
 }
 
-func (w *WeatherAPIService) GetWeatherByCity(city string) (*WeatherAPIResponse, error) {
-	base, _ := url.Parse(WeatherAPI_URL)
+// forecast returns the forecast of days for a WeatherAPI "q" parameter
+func (w *WeatherAPIService) forecast(ctx context.Context, query string, days int) (*Forecast, error) {
 	params := url.Values{}
-	params.Add("key", w.apiKey)
-	params.Add("q", city)
+	params.Add("q", query)
+	params.Add("days", strconv.Itoa(days))
+	params.Add("aqi", "no")
+	params.Add("alerts", "no")
+	var forecastResponse WeatherAPIForecastResponse
+	err := w.getJSON(ctx, urlFor(w.ForecastURL, WeatherAPI_ForecastURL), params, &forecastResponse)
+	if err != nil {
+		return nil, err
+	}
+	return forecastResponse.ToForecast(), nil
+}
+
+// history returns the observed weather of the days from and to for a
+// WeatherAPI "q" parameter
+func (w *WeatherAPIService) history(ctx context.Context, query string, from, to time.Time) (*History, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	params.Add("dt", from.Format(time.DateOnly))
+	if !to.Equal(from) {
+		params.Add("end_dt", to.Format(time.DateOnly))
+	}
+	var historyResponse WeatherAPIForecastResponse
+	err := w.getJSON(ctx, urlFor(w.HistoryURL, WeatherAPI_HistoryURL), params, &historyResponse)
+	if err != nil {
+		return nil, err
+	}
+	return (*History)(historyResponse.ToForecast()), nil
+}
+
+// getJSON fetches rawURL with the API key and params and decodes the response
+// into out
+func (w *WeatherAPIService) getJSON(ctx context.Context, rawURL string, params url.Values, out any) error {
+	ctx, cancel := w.withTimeout(ctx)
+	defer cancel()
+
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	params.Set("key", w.apiKey)
 	base.RawQuery = params.Encode()
-	resp, err := w.Client.Get(base.String())
+	resp, err := w.get(ctx, base.String())
 	if err != nil {
 		log.Println("error getting weather: ", err)
-		return nil, err
+		return &UpstreamError{Service: WeatherProviderWeatherAPI, Kind: ErrUpstreamUnavailable, Err: err}
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		log.Printf("error getting weather: statusCode:%d Response:%s\n", resp.StatusCode, resp.Body)
-		return nil, fmt.Errorf("error getting weather: %d", resp.StatusCode)
-	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("error reading response body: ", err)
-		return nil, err
+		return &UpstreamError{Service: WeatherProviderWeatherAPI, Kind: ErrUpstreamUnavailable, Err: err}
+	} else if resp.StatusCode != 200 {
+		log.Printf("error getting weather: statusCode:%d Response:%s\n", resp.StatusCode, body)
+		return newWeatherAPIError(resp.StatusCode, body)
 	}
 
-	var weatherResponse WeatherAPIResponse
-	err = json.Unmarshal(body, &weatherResponse)
+	err = json.Unmarshal(body, out)
 	if err != nil {
 		log.Println("error on Unmarshal response body: ", err)
-		return nil, err
+		return &UpstreamError{Service: WeatherProviderWeatherAPI, Kind: ErrMalformedResponse, Err: err}
 	}
+	return nil
+}
+
+// newWeatherAPIError maps an unsuccessful WeatherAPI response to an
+// UpstreamError, using the error code in its body when there is one
+func newWeatherAPIError(status int, body []byte) *UpstreamError {
+	var apiError WeatherAPIError
+	json.Unmarshal(body, &apiError)
+
+	var kind error
+	switch apiError.Error.Code {
+	case WeatherAPI_ErrNoLocation:
+		kind = ErrLocationNotFound
+	case WeatherAPI_ErrKeyNotProvided, WeatherAPI_ErrInvalidKey, WeatherAPI_ErrKeyDisabled, WeatherAPI_ErrNoAccess:
+		kind = ErrUnauthorized
+	case WeatherAPI_ErrQuotaExceeded:
+		kind = ErrRateLimited
+	case WeatherAPI_ErrInternal:
+		kind = ErrUpstreamUnavailable
+	default:
+		kind = statusKind(status, ErrUpstreamUnavailable)
+		if kind == nil {
+			// Any other 4xx rejects the request itself, which retrying does
+			// not fix
+			kind = ErrMalformedResponse
+		}
+	}
+	return &UpstreamError{
+		Service:    WeatherProviderWeatherAPI,
+		Kind:       kind,
+		StatusCode: status,
+		Code:       apiError.Error.Code,
+		Message:    apiError.Error.Message,
+	}
+}
+
+// ToWeather converts the WeatherAPI response into a Weather
+func (r *WeatherAPIResponse) ToWeather() *Weather {
+	return &Weather{
+		Location: r.weatherLocation(),
+		Current: CurrentWeather{
+			ObservedAt: time.Unix(int64(r.Current.LastUpdatedEpoch), 0).UTC(),
+			TempC:      r.Current.TempC,
+			TempF:      r.Current.TempF,
+			FeelsLikeC: r.Current.FeelslikeC,
+			FeelsLikeF: r.Current.FeelslikeF,
+			IsDay:      r.Current.IsDay == 1,
+			Condition:  r.Current.Condition.Text,
+			WindKph:    r.Current.WindKph,
+			WindDegree: r.Current.WindDegree,
+			WindDir:    r.Current.WindDir,
+			GustKph:    r.Current.GustKph,
+			PressureMb: r.Current.PressureMb,
+			PrecipMm:   r.Current.PrecipMm,
+			Humidity:   r.Current.Humidity,
+			Cloud:      r.Current.Cloud,
+			VisKm:      r.Current.VisKm,
+			Uv:         r.Current.Uv,
+		},
+		Provider: WeatherProviderWeatherAPI,
+	}
+}
+
+// ToForecast converts the WeatherAPI forecast response into a Forecast
+func (r *WeatherAPIForecastResponse) ToForecast() *Forecast {
+	location, err := time.LoadLocation(r.Location.TzID)
+	if err != nil {
+		location = time.UTC
+	}
+	forecast := &Forecast{
+		Location: r.weatherLocation(),
+		Days:     make([]ForecastDay, 0, len(r.Forecast.Forecastday)),
+		Provider: WeatherProviderWeatherAPI,
+	}
+	for _, day := range r.Forecast.Forecastday {
+		date, err := time.ParseInLocation("2006-01-02", day.Date, location)
+		if err != nil {
+			date = time.Unix(int64(day.DateEpoch), 0).In(location)
+		}
+		forecastDay := ForecastDay{
+			Date:         date,
+			MinTempC:     day.Day.MintempC,
+			MinTempF:     day.Day.MintempF,
+			MaxTempC:     day.Day.MaxtempC,
+			MaxTempF:     day.Day.MaxtempF,
+			AvgTempC:     day.Day.AvgtempC,
+			ChanceOfRain: day.Day.DailyChanceOfRain,
+			PrecipMm:     day.Day.TotalprecipMm,
+			Condition:    day.Day.Condition.Text,
+			Hours:        make([]ForecastHour, 0, len(day.Hour)),
+		}
+		for _, hour := range day.Hour {
+			forecastDay.Hours = append(forecastDay.Hours, ForecastHour{
+				Time:         time.Unix(int64(hour.TimeEpoch), 0).In(location),
+				TempC:        hour.TempC,
+				TempF:        hour.TempF,
+				ChanceOfRain: hour.ChanceOfRain,
+				Condition:    hour.Condition.Text,
+				IsDay:        hour.IsDay == 1,
+			})
+		}
+		forecast.Days = append(forecast.Days, forecastDay)
+	}
+	return forecast
+}
 
-	return &weatherResponse, nil
+// weatherLocation converts the WeatherAPI location into a WeatherLocation
+func (r *WeatherAPIResponse) weatherLocation() WeatherLocation {
+	return WeatherLocation{
+		Name:    r.Location.Name,
+		Region:  r.Location.Region,
+		Country: r.Location.Country,
+		Lat:     r.Location.Lat,
+		Lon:     r.Location.Lon,
+		TzID:    r.Location.TzID,
+	}
 }
@@ -2,10 +2,15 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"regexp"
 
@@ -70,11 +75,12 @@ func (br *brokenReader) Close() error {
 	return fmt.Errorf("failed closing")
 }
 
-func (m *mockWeatherApiHTTPClient) Get(url string) (*http.Response, error) {
+func (m *mockWeatherApiHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
 	re := regexp.MustCompile(`^.*=.*=(.*)$`)
 	match := re.FindStringSubmatch(url)
 	switch match[1] {
-	case "Florian%C3%B3polis":
+	case "Florian%C3%B3polis", "-27.58%2C-48.57":
 		response := &http.Response{
 			StatusCode: 200,
 			Body:       io.NopCloser(bytes.NewBufferString(weatherAPIBody)),
@@ -88,6 +94,13 @@ func (m *mockWeatherApiHTTPClient) Get(url string) (*http.Response, error) {
 			Header:     make(http.Header),
 		}
 		return response, nil
+	case "Nowhere":
+		response := &http.Response{
+			StatusCode: 400,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"error":{"code":1006,"message":"No matching location found."}}`)),
+			Header:     make(http.Header),
+		}
+		return response, nil
 	case "BrokenReader":
 		response := &http.Response{
 			StatusCode: 200,
@@ -115,7 +128,7 @@ func TestGetWeatherByCity(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
 	}
 
-	result, err := service.GetWeatherByCity("Florianópolis")
+	result, err := service.GetWeatherByCity(context.Background(), "Florianópolis")
 
 	// Assert there was no error
 	assert.Nil(t, err)
@@ -128,34 +141,39 @@ func TestGetWeatherByCity(t *testing.T) {
 	assert.Equal(float64(-27.58), result.Location.Lat)
 	assert.Equal(float64(-48.57), result.Location.Lon)
 	assert.Equal("America/Sao_Paulo", result.Location.TzID)
-	assert.Equal(int(1716578742), result.Location.LocaltimeEpoch)
-	assert.Equal("2024-07-01 16:25", result.Location.Localtime)
 
-	assert.Equal(int(1716578100), result.Current.LastUpdatedEpoch)
-	assert.Equal("2024-07-01 16:15", result.Current.LastUpdated)
+	assert.Equal(time.Unix(1716578100, 0).UTC(), result.Current.ObservedAt)
 	assert.Equal(float64(21), result.Current.TempC)
 	assert.Equal(float64(69.8), result.Current.TempF)
-	assert.Equal(1, result.Current.IsDay)
-	assert.Equal("Overcast", result.Current.Condition.Text)
-	assert.Equal("//cdn.weatherapi.com/weather/64x64/day/122.png", result.Current.Condition.Icon)
-	assert.Equal(1009, result.Current.Condition.Code)
-	assert.Equal(float64(8.1), result.Current.WindMph)
+	assert.Equal(true, result.Current.IsDay)
+	assert.Equal("Overcast", result.Current.Condition)
 	assert.Equal(float64(13), result.Current.WindKph)
 	assert.Equal(110, result.Current.WindDegree)
 	assert.Equal("ESE", result.Current.WindDir)
 	assert.Equal(float64(1008), result.Current.PressureMb)
-	assert.Equal(float64(29.77), result.Current.PressureIn)
 	assert.Equal(float64(0.04), result.Current.PrecipMm)
-	assert.Equal(float64(0), result.Current.PrecipIn)
 	assert.Equal(83, result.Current.Humidity)
 	assert.Equal(100, result.Current.Cloud)
-	assert.Equal(float64(21), result.Current.FeelslikeC)
-	assert.Equal(float64(69.8), result.Current.FeelslikeF)
+	assert.Equal(float64(21), result.Current.FeelsLikeC)
+	assert.Equal(float64(69.8), result.Current.FeelsLikeF)
 	assert.Equal(float64(10), result.Current.VisKm)
-	assert.Equal(float64(6), result.Current.VisMiles)
 	assert.Equal(float64(5), result.Current.Uv)
-	assert.Equal(float64(12.5), result.Current.GustMph)
 	assert.Equal(float64(20.2), result.Current.GustKph)
+	assert.Equal(WeatherProviderWeatherAPI, result.Provider)
+}
+
+func TestGetWeatherByCoordinates(t *testing.T) {
+
+	service := &WeatherAPIService{
+		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
+	}
+
+	result, err := service.GetWeatherByCoordinates(context.Background(), -27.58, -48.57)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("Florianópolis", result.Location.Name)
+	assert.Equal(float64(21), result.Current.TempC)
 }
 
 func TestGetWeatherNot200StatusCode(t *testing.T) {
@@ -164,10 +182,11 @@ func TestGetWeatherNot200StatusCode(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
 	}
 
-	_, err := service.GetWeatherByCity("Erroropolis")
+	_, err := service.GetWeatherByCity(context.Background(), "Erroropolis")
 
 	assert := assert.New(t)
-	assert.Equal(fmt.Errorf("error getting weather: 400"), err)
+	assert.ErrorIs(err, ErrMalformedResponse)
+	assert.Equal("weatherapi: malformed upstream response: status 400", err.Error())
 
 }
 
@@ -177,10 +196,11 @@ func TestGetWeatherBadBody(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
 	}
 
-	_, err := service.GetWeatherByCity("RequestFail")
+	_, err := service.GetWeatherByCity(context.Background(), "RequestFail")
 
 	assert := assert.New(t)
-	assert.Equal("error getting weather: 400", err.Error())
+	assert.ErrorIs(err, ErrUpstreamUnavailable)
+	assert.Equal("weatherapi: upstream service unavailable: error getting weather: 400", err.Error())
 }
 
 func TestGetWeatherUnmarshalError(t *testing.T) {
@@ -189,10 +209,15 @@ func TestGetWeatherUnmarshalError(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
 	}
 
-	_, err := service.GetWeatherByCity("UnmarshalError")
+	_, err := service.GetWeatherByCity(context.Background(), "UnmarshalError")
 
 	assert := assert.New(t)
-	assert.Equal("json: cannot unmarshal string into Go struct field WeatherAPIResponseCurrent.current.feelslike_f of type float64", err.Error())
+	// The struct name in the message changed between Go releases, so match
+	// on the decoded error instead of its text.
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(err, &typeErr)
+	assert.Equal("current.feelslike_f", typeErr.Field)
+	assert.Equal("string", typeErr.Value)
 }
 
 func TestGetWeatherReaderError(t *testing.T) {
@@ -201,8 +226,199 @@ func TestGetWeatherReaderError(t *testing.T) {
 		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
 	}
 
-	_, err := service.GetWeatherByCity("BrokenReader")
+	_, err := service.GetWeatherByCity(context.Background(), "BrokenReader")
+
+	assert := assert.New(t)
+	assert.ErrorIs(err, ErrUpstreamUnavailable)
+	assert.Equal("weatherapi: upstream service unavailable: failed reading", err.Error())
+}
+
+func TestGetWeatherLocationNotFound(t *testing.T) {
+
+	service := &WeatherAPIService{
+		BaseHttpService: BaseHttpService{Client: &mockWeatherApiHTTPClient{}},
+	}
+
+	_, err := service.GetWeatherByCity(context.Background(), "Nowhere")
+
+	assert := assert.New(t)
+	assert.ErrorIs(err, ErrLocationNotFound)
+	var upstreamErr *UpstreamError
+	assert.ErrorAs(err, &upstreamErr)
+	assert.Equal(WeatherAPI_ErrNoLocation, upstreamErr.Code)
+	assert.Equal("No matching location found.", upstreamErr.Message)
+}
+
+func TestNewWeatherAPIError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		kind   error
+	}{
+		{400, `{"error":{"code":1006,"message":"No matching location found."}}`, ErrLocationNotFound},
+		{401, `{"error":{"code":2006,"message":"API key is invalid."}}`, ErrUnauthorized},
+		{403, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, ErrRateLimited},
+		{403, `{"error":{"code":2008,"message":"API key has been disabled."}}`, ErrUnauthorized},
+		{400, `{"error":{"code":9999,"message":"Internal application error."}}`, ErrUpstreamUnavailable},
+		{429, ``, ErrRateLimited},
+		{502, `<html>Bad Gateway</html>`, ErrUpstreamUnavailable},
+		{400, `{"error":{"code":1003,"message":"Parameter q is missing."}}`, ErrMalformedResponse},
+		{400, `{"error":{"code":1005,"message":"API request url is invalid."}}`, ErrMalformedResponse},
+		{404, ``, ErrMalformedResponse},
+		{400, ``, ErrMalformedResponse},
+	}
+	for _, test := range tests {
+		err := newWeatherAPIError(test.status, []byte(test.body))
+		assert.ErrorIs(t, err, test.kind, test.body)
+		assert.Equal(t, test.status, err.StatusCode)
+	}
+}
+
+const weatherAPIForecastBody = `{
+	"location": {
+		"name": "Florianópolis",
+		"region": "Santa Catarina",
+		"country": "Brazil",
+		"lat": -27.58,
+		"lon": -48.57,
+		"tz_id": "America/Sao_Paulo"
+	},
+	"current": {"temp_c": 21, "temp_f": 69.8},
+	"forecast": {
+		"forecastday": [
+			{
+				"date": "2024-05-24",
+				"date_epoch": 1716508800,
+				"day": {
+					"maxtemp_c": 24.1, "maxtemp_f": 75.4,
+					"mintemp_c": 17.3, "mintemp_f": 63.1,
+					"avgtemp_c": 20.2, "avgtemp_f": 68.4,
+					"totalprecip_mm": 3.5,
+					"daily_chance_of_rain": 86,
+					"condition": {"text": "Patchy rain nearby", "code": 1063}
+				},
+				"hour": [
+					{"time_epoch": 1716519600, "time": "2024-05-24 00:00", "temp_c": 18.2, "temp_f": 64.8, "is_day": 0, "chance_of_rain": 0, "condition": {"text": "Clear"}},
+					{"time_epoch": 1716562800, "time": "2024-05-24 12:00", "temp_c": 23.9, "temp_f": 75, "is_day": 1, "chance_of_rain": 86, "condition": {"text": "Patchy rain nearby"}}
+				]
+			}
+		]
+	}
+}`
+
+func TestGetForecastByCoordinates(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(weatherAPIForecastBody))
+	}))
+	defer server.Close()
+	service := NewWeatherAPIService("secret", server.Client()).(*WeatherAPIService)
+	service.ForecastURL = server.URL
+
+	forecast, err := service.GetForecastByCoordinates(context.Background(), -27.58, -48.57, 2)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("secret", query.Get("key"))
+	assert.Equal("-27.58,-48.57", query.Get("q"))
+	assert.Equal("2", query.Get("days"))
+	assert.Equal("Florianópolis", forecast.Location.Name)
+	assert.Equal(WeatherProviderWeatherAPI, forecast.Provider)
+	assert.Len(forecast.Days, 1)
+
+	day := forecast.Days[0]
+	assert.Equal("2024-05-24T00:00:00-03:00", day.Date.Format(time.RFC3339))
+	assert.Equal(17.3, day.MinTempC)
+	assert.Equal(63.1, day.MinTempF)
+	assert.Equal(24.1, day.MaxTempC)
+	assert.Equal(75.4, day.MaxTempF)
+	assert.Equal(86, day.ChanceOfRain)
+	assert.Equal(3.5, day.PrecipMm)
+	assert.Equal("Patchy rain nearby", day.Condition)
+	assert.Len(day.Hours, 2)
+	assert.Equal("2024-05-24T12:00:00-03:00", day.Hours[1].Time.Format(time.RFC3339))
+	assert.Equal(23.9, day.Hours[1].TempC)
+	assert.Equal(float64(75), day.Hours[1].TempF)
+	assert.Equal(86, day.Hours[1].ChanceOfRain)
+	assert.True(day.Hours[1].IsDay)
+}
+
+func TestGetForecastLocationNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":1006,"message":"No matching location found."}}`))
+	}))
+	defer server.Close()
+	service := NewWeatherAPIService("secret", server.Client()).(*WeatherAPIService)
+	service.ForecastURL = server.URL
+
+	forecast, err := service.GetForecastByCity(context.Background(), "Nowhere", 3)
+
+	assert.Nil(t, forecast)
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+}
+
+const weatherAPIHistoryBody = `{
+	"location": {"name": "Florianópolis", "region": "Santa Catarina", "country": "Brazil", "tz_id": "America/Sao_Paulo"},
+	"forecast": {
+		"forecastday": [
+			{
+				"date": "2024-05-20",
+				"day": {"maxtemp_c": 22, "maxtemp_f": 71.6, "mintemp_c": 15.5, "mintemp_f": 59.9, "totalprecip_mm": 0, "daily_chance_of_rain": 0, "condition": {"text": "Sunny"}},
+				"hour": [{"time_epoch": 1716217200, "temp_c": 16.1, "temp_f": 61, "is_day": 0, "condition": {"text": "Clear"}}]
+			},
+			{
+				"date": "2024-05-21",
+				"day": {"maxtemp_c": 23.4, "maxtemp_f": 74.1, "mintemp_c": 16, "mintemp_f": 60.8, "totalprecip_mm": 1.2, "daily_chance_of_rain": 100, "condition": {"text": "Light rain"}},
+				"hour": []
+			}
+		]
+	}
+}`
+
+func TestGetHistoryByCity(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(weatherAPIHistoryBody))
+	}))
+	defer server.Close()
+	service := NewWeatherAPIService("secret", server.Client()).(*WeatherAPIService)
+	service.HistoryURL = server.URL
+
+	history, err := service.GetHistoryByCity(context.Background(), "Florianópolis", time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 21, 0, 0, 0, 0, time.UTC))
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("Florianópolis", query.Get("q"))
+	assert.Equal("2024-05-20", query.Get("dt"))
+	assert.Equal("2024-05-21", query.Get("end_dt"))
+	assert.Equal(WeatherProviderWeatherAPI, history.Provider)
+	assert.Len(history.Days, 2)
+	assert.Equal("2024-05-20", history.Days[0].Date.Format(time.DateOnly))
+	assert.Equal(15.5, history.Days[0].MinTempC)
+	assert.Equal("Clear", history.Days[0].Hours[0].Condition)
+	assert.Equal(100, history.Days[1].ChanceOfRain)
+	assert.Equal("Light rain", history.Days[1].Condition)
+}
+
+func TestGetHistorySingleDay(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(weatherAPIHistoryBody))
+	}))
+	defer server.Close()
+	service := NewWeatherAPIService("secret", server.Client()).(*WeatherAPIService)
+	service.HistoryURL = server.URL
+	day := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.GetHistoryByCoordinates(context.Background(), -27.58, -48.57, day, day)
 
 	assert := assert.New(t)
-	assert.Equal("failed reading", err.Error())
+	assert.Nil(err)
+	assert.Equal("-27.58,-48.57", query.Get("q"))
+	assert.Equal("2024-05-20", query.Get("dt"))
+	assert.False(query.Has("end_dt"))
 }
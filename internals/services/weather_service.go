@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcbadiale/go-cloud-run/internals"
+)
+
+const (
+	WeatherProviderWeatherAPI = "weatherapi"
+	WeatherProviderOpenMeteo  = "openmeteo"
+)
+
+type WeatherService interface {
+	GetWeatherByCity(ctx context.Context, city string) (*Weather, error)
+	GetWeatherByCoordinates(ctx context.Context, lat, lon float64) (*Weather, error)
+}
+
+// Weather is the provider-neutral weather returned by every WeatherService
+type Weather struct {
+	Location WeatherLocation `json:"location"`
+	Current  CurrentWeather  `json:"current"`
+	Provider string          `json:"provider"`
+}
+
+type WeatherLocation struct {
+	Name    string  `json:"name"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	TzID    string  `json:"tz_id"`
+}
+
+// CurrentWeather holds the current conditions in metric units
+type CurrentWeather struct {
+	ObservedAt time.Time `json:"observed_at"`
+	TempC      float64   `json:"temp_c"`
+	TempF      float64   `json:"temp_f"`
+	FeelsLikeC float64   `json:"feelslike_c"`
+	FeelsLikeF float64   `json:"feelslike_f"`
+	IsDay      bool      `json:"is_day"`
+	Condition  string    `json:"condition"`
+	WindKph    float64   `json:"wind_kph"`
+	WindDegree int       `json:"wind_degree"`
+	WindDir    string    `json:"wind_dir"`
+	GustKph    float64   `json:"gust_kph"`
+	PressureMb float64   `json:"pressure_mb"`
+	PrecipMm   float64   `json:"precip_mm"`
+	Humidity   int       `json:"humidity"`
+	Cloud      int       `json:"cloud"`
+	VisKm      float64   `json:"vis_km"`
+	Uv         float64   `json:"uv"`
+}
+
+// NewWeatherService creates the WeatherService registered under provider,
+// WeatherAPI being used when none is given
+func NewWeatherService(provider string, weatherApiKey string, newClient internals.ClientFactory) (WeatherService, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "", WeatherProviderWeatherAPI:
+		return NewWeatherAPIService(weatherApiKey, newClient(WeatherProviderWeatherAPI)), nil
+	case WeatherProviderOpenMeteo:
+		return NewOpenMeteoService(newClient(WeatherProviderOpenMeteo)), nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider: %q", provider)
+	}
+}
+
+// formatCoordinates formats coordinates as "lat,lon"
+func formatCoordinates(lat, lon float64) string {
+	return strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64)
+}
+
+var compassPoints = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// compassDirection converts a wind direction in degrees to a 16-point compass
+func compassDirection(degree int) string {
+	index := int(float64((degree%360+360)%360)/22.5+0.5) % len(compassPoints)
+	return compassPoints[index]
+}
@@ -0,0 +1,56 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode is how values are rounded to a number of decimal places
+type RoundingMode string
+
+const (
+	// HalfEven rounds to the nearest value, ties to the even digit
+	HalfEven RoundingMode = "half-even"
+	// HalfUp rounds to the nearest value, ties away from zero
+	HalfUp RoundingMode = "half-up"
+	// Truncate drops the extra digits, rounding towards zero
+	Truncate RoundingMode = "truncate"
+)
+
+// ParseRoundingMode parses a rounding mode name, case-insensitively
+func ParseRoundingMode(value string) (RoundingMode, error) {
+	mode := RoundingMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case HalfEven, HalfUp, Truncate:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q, expected %s, %s or %s", value, HalfEven, HalfUp, Truncate)
+	}
+}
+
+// Round rounds value to precision decimal places using mode
+func Round(value float64, precision int, mode RoundingMode) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+	scale := math.Pow10(precision)
+	// Scaling leaves binary artifacts, e.g. 297.1 * 10 = 2970.9999999999995,
+	// so the scaled value is cut to 15 significant digits, as many as a
+	// float64 represents exactly, before rounding it
+	scaled, _ := strconv.ParseFloat(strconv.FormatFloat(value*scale, 'g', 15, 64), 64)
+	switch mode {
+	case Truncate:
+		scaled = math.Trunc(scaled)
+	case HalfEven:
+		scaled = math.RoundToEven(scaled)
+	default:
+		scaled = math.Round(scaled)
+	}
+	if scaled == 0 {
+		// Avoids reporting negative zero, e.g. -0.04 rounded to -0
+		return 0
+	}
+	return scaled / scale
+}
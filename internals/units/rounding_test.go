@@ -0,0 +1,44 @@
+package units
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoundingMode(t *testing.T) {
+	assert := assert.New(t)
+	mode, err := ParseRoundingMode("Half-Even")
+	assert.Nil(err)
+	assert.Equal(HalfEven, mode)
+	_, err = ParseRoundingMode("ceil")
+	assert.EqualError(err, `unknown rounding mode "ceil", expected half-even, half-up or truncate`)
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value     float64
+		precision int
+		mode      RoundingMode
+		expected  float64
+	}{
+		{1.25, 1, HalfUp, 1.3},
+		{1.25, 1, HalfEven, 1.2},
+		{1.35, 1, HalfEven, 1.4},
+		{1.29, 1, Truncate, 1.2},
+		{-1.25, 1, HalfUp, -1.3},
+		{-1.25, 1, HalfEven, -1.2},
+		{-1.29, 1, Truncate, -1.2},
+		{-0.04, 1, HalfUp, 0},
+		{23.95 + 273.15, 1, Truncate, 297.1},
+		{1.005, 2, HalfUp, 1.01},
+		{289.14999, 0, HalfUp, 289},
+		{289.5, 0, HalfEven, 290},
+		{12.3456, 3, HalfUp, 12.346},
+	}
+	assert.False(t, math.Signbit(Round(-0.04, 1, HalfUp)))
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Round(test.value, test.precision, test.mode), "%v %d %s", test.value, test.precision, test.mode)
+	}
+}
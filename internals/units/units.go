@@ -0,0 +1,81 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+// System is a set of units used to report measurements
+type System string
+
+const (
+	// Metric uses Celsius, km/h, millibar, millimetre and kilometre
+	Metric System = "metric"
+	// Imperial uses Fahrenheit, mph, inches of mercury, inch and mile
+	Imperial System = "imperial"
+	// SI uses Kelvin, m/s, pascal, millimetre and metre
+	SI System = "si"
+	// All reports every unit of the other systems
+	All System = "all"
+)
+
+// ParseSystem parses a unit system name, case-insensitively
+func ParseSystem(value string) (System, error) {
+	system := System(strings.ToLower(strings.TrimSpace(value)))
+	switch system {
+	case Metric, Imperial, SI, All:
+		return system, nil
+	default:
+		return "", fmt.Errorf("unknown unit system %q, expected %s, %s, %s or %s", value, Metric, Imperial, SI, All)
+	}
+}
+
+// Includes reports whether measurements in system are reported by s
+func (s System) Includes(system System) bool {
+	return s == All || s == system
+}
+
+// CelsiusToFahrenheit converts a temperature from Celsius to Fahrenheit
+func CelsiusToFahrenheit(celsius float64) float64 {
+	return celsius*9/5 + 32
+}
+
+// CelsiusToKelvin converts a temperature from Celsius to Kelvin
+func CelsiusToKelvin(celsius float64) float64 {
+	return celsius + 273.15
+}
+
+// KphToMph converts a speed from kilometres to miles per hour
+func KphToMph(kph float64) float64 {
+	return kph / 1.609344
+}
+
+// KphToMps converts a speed from kilometres per hour to metres per second
+func KphToMps(kph float64) float64 {
+	return kph / 3.6
+}
+
+// MillibarToInHg converts a pressure from millibar to inches of mercury
+func MillibarToInHg(mb float64) float64 {
+	return mb / 33.8639
+}
+
+// MillibarToPascal converts a pressure from millibar to pascal
+func MillibarToPascal(mb float64) float64 {
+	return mb * 100
+}
+
+// MillimetreToInch converts a length from millimetres to inches
+func MillimetreToInch(mm float64) float64 {
+	return mm / 25.4
+}
+
+// KilometreToMile converts a length from kilometres to miles
+func KilometreToMile(km float64) float64 {
+	return km / 1.609344
+}
+
+// KilometreToMetre converts a length from kilometres to metres
+func KilometreToMetre(km float64) float64 {
+	return km * 1000
+}
@@ -0,0 +1,40 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSystem(t *testing.T) {
+	assert := assert.New(t)
+	for _, value := range []string{"metric", "Imperial", " si ", "ALL"} {
+		_, err := ParseSystem(value)
+		assert.Nil(err, value)
+	}
+	_, err := ParseSystem("nautical")
+	assert.EqualError(err, `unknown unit system "nautical", expected metric, imperial, si or all`)
+}
+
+func TestSystemIncludes(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(Metric.Includes(Metric))
+	assert.False(Metric.Includes(Imperial))
+	assert.True(All.Includes(Imperial))
+	assert.True(All.Includes(SI))
+}
+
+func TestConversions(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(float64(32), CelsiusToFahrenheit(0))
+	assert.Equal(float64(-40), CelsiusToFahrenheit(-40))
+	assert.Equal(float64(212), CelsiusToFahrenheit(100))
+	assert.Equal(273.15, CelsiusToKelvin(0))
+	assert.InDelta(62.137, KphToMph(100), 0.001)
+	assert.InDelta(10, KphToMps(36), 1e-9)
+	assert.InDelta(29.921, MillibarToInHg(1013.25), 0.001)
+	assert.Equal(float64(101325), MillibarToPascal(1013.25))
+	assert.Equal(float64(1), MillimetreToInch(25.4))
+	assert.InDelta(6.214, KilometreToMile(10), 0.001)
+	assert.Equal(float64(1500), KilometreToMetre(1.5))
+}